@@ -4,8 +4,10 @@ go 1.25.0
 
 require (
 	github.com/edgexfoundry/device-sdk-go/v4 v4.1.0-dev.60
+	github.com/edgexfoundry/go-mod-bootstrap/v4 v4.1.0-dev.63
 	github.com/edgexfoundry/go-mod-core-contracts/v4 v4.1.0-dev.32
 	github.com/gopcua/opcua v0.8.0
+	github.com/rcrowley/go-metrics v0.0.0-20250401214520-65e299d6c5c9
 	github.com/spf13/cast v1.10.0
 	github.com/stretchr/testify v1.11.1
 )
@@ -17,7 +19,6 @@ require (
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
 	github.com/eclipse/paho.mqtt.golang v1.5.1 // indirect
-	github.com/edgexfoundry/go-mod-bootstrap/v4 v4.1.0-dev.63 // indirect
 	github.com/edgexfoundry/go-mod-configuration/v4 v4.1.0-dev.18 // indirect
 	github.com/edgexfoundry/go-mod-messaging/v4 v4.1.0-dev.23 // indirect
 	github.com/edgexfoundry/go-mod-registry/v4 v4.1.0-dev.9 // indirect
@@ -100,7 +101,6 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/power-devops/perfstat v0.0.0-20210106213030-5aafc221ea8c // indirect
-	github.com/rcrowley/go-metrics v0.0.0-20250401214520-65e299d6c5c9 // indirect
 	github.com/shirou/gopsutil/v3 v3.24.5 // indirect
 	github.com/shoenig/go-m1cpu v0.1.6 // indirect
 	github.com/sirupsen/logrus v1.9.4 // indirect
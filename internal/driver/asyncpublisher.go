@@ -0,0 +1,156 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v4/bootstrap/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/clients/logger"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+const (
+	// defaultAsyncBufferSize is the number of async values queued for the SDK by default
+	defaultAsyncBufferSize = 100
+	// names of the service metrics counting the readings dropped from the queue and not queued
+	asyncReadingsDroppedOldestName = "AsyncReadingsDroppedOldest"
+	asyncReadingsDroppedNewestName = "AsyncReadingsDroppedNewest"
+)
+
+// asyncPublisher queues the async values of subscriptions and forwards them to the SDK
+// from its own goroutine, so that a busy SDK channel does not stall the driver. When the
+// queue is full, the overflow policy blocks the subscription or drops values.
+type asyncPublisher struct {
+	queue  chan *sdkModels.AsyncValues
+	policy string
+	logger logger.LoggingClient
+	// numbers of readings dropped from the queue and not queued
+	droppedOldest gometrics.Counter
+	droppedNewest gometrics.Counter
+}
+
+func newAsyncPublisher(size int, policy string, lc logger.LoggingClient) *asyncPublisher {
+	if size <= 0 {
+		size = defaultAsyncBufferSize
+	}
+	if policy == "" {
+		policy = OverflowBlock
+	}
+	return &asyncPublisher{
+		queue:         make(chan *sdkModels.AsyncValues, size),
+		policy:        policy,
+		logger:        lc,
+		droppedOldest: gometrics.NewCounter(),
+		droppedNewest: gometrics.NewCounter(),
+	}
+}
+
+// registerMetrics registers the counters of dropped readings as service metrics, reported
+// when enabled in Writable.Telemetry.Metrics
+func (p *asyncPublisher) registerMetrics(manager bootstrapInterfaces.MetricsManager) {
+	counters := map[string]gometrics.Counter{
+		asyncReadingsDroppedOldestName: p.droppedOldest,
+		asyncReadingsDroppedNewestName: p.droppedNewest,
+	}
+	for name, counter := range counters {
+		if err := manager.Register(name, counter, map[string]string{"policy": p.policy}); err != nil {
+			p.logger.Warnf("Unable to register metric %s: %v", name, err)
+		}
+	}
+}
+
+// unregisterMetrics unregisters the counters of dropped readings
+func (p *asyncPublisher) unregisterMetrics(manager bootstrapInterfaces.MetricsManager) {
+	manager.Unregister(asyncReadingsDroppedOldestName)
+	manager.Unregister(asyncReadingsDroppedNewestName)
+}
+
+// run forwards the queued values to the SDK until ctx is cancelled
+func (p *asyncPublisher) run(ctx context.Context, out chan<- *sdkModels.AsyncValues) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case values := <-p.queue:
+			select {
+			case out <- values:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// publish queues async values according to the overflow policy, and reports whether they were queued
+func (p *asyncPublisher) publish(ctx context.Context, values *sdkModels.AsyncValues) bool {
+	switch p.policy {
+	case OverflowDropNewest:
+		select {
+		case p.queue <- values:
+			return true
+		default:
+			p.dropped(p.droppedNewest, values)
+			return false
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case p.queue <- values:
+				return true
+			default:
+			}
+			// make room by dropping the oldest values, unless they were just forwarded
+			select {
+			case oldest := <-p.queue:
+				p.dropped(p.droppedOldest, oldest)
+			default:
+			}
+		}
+	default:
+		select {
+		case p.queue <- values:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// dropped counts the readings of dropped values. Warnings are logged for the first
+// dropped reading and then at every power of ten, so that an overflow does not flood the log.
+func (p *asyncPublisher) dropped(counter gometrics.Counter, values *sdkModels.AsyncValues) {
+	for range values.CommandValues {
+		counter.Inc(1)
+		if total := counter.Count(); isPowerOfTen(uint64(total)) { // #nosec G115
+			p.logger.Warnf("[Incoming listener] Async queue full, %d readings dropped with the %s policy, latest from device %s",
+				total, p.policy, values.DeviceName)
+		}
+	}
+}
+
+// droppedReadings returns the numbers of readings dropped from the queue and not queued
+func (p *asyncPublisher) droppedReadings() (oldest, newest uint64) {
+	return uint64(p.droppedOldest.Count()), uint64(p.droppedNewest.Count()) // #nosec G115
+}
+
+func isPowerOfTen(n uint64) bool {
+	for n >= 10 && n%10 == 0 {
+		n /= 10
+	}
+	return n == 1
+}
+
+// publishAsync sends async values to the SDK through the publisher of the driver
+func (d *Driver) publishAsync(values *sdkModels.AsyncValues) {
+	if d.publisher == nil {
+		d.AsyncCh <- values
+		return
+	}
+	d.publisher.publish(d.serviceContext(), values)
+}
@@ -0,0 +1,110 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	bootstrapInterfaces "github.com/edgexfoundry/go-mod-bootstrap/v4/bootstrap/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/clients/logger"
+	gometrics "github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func asyncValues(deviceName string) *sdkModels.AsyncValues {
+	return &sdkModels.AsyncValues{DeviceName: deviceName, CommandValues: []*sdkModels.CommandValue{{DeviceResourceName: "Counter"}}}
+}
+
+func Test_asyncPublisher_publish(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		wantQueued []string
+		wantOldest uint64
+		wantNewest uint64
+	}{
+		{name: "drop newest", policy: OverflowDropNewest, wantQueued: []string{"first", "second"}, wantNewest: 1},
+		{name: "drop oldest", policy: OverflowDropOldest, wantQueued: []string{"second", "third"}, wantOldest: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newAsyncPublisher(2, tt.policy, &logger.MockLogger{})
+			assert.True(t, p.publish(context.Background(), asyncValues("first")))
+			assert.True(t, p.publish(context.Background(), asyncValues("second")))
+			p.publish(context.Background(), asyncValues("third"))
+
+			require.Len(t, p.queue, len(tt.wantQueued))
+			for _, want := range tt.wantQueued {
+				assert.Equal(t, want, (<-p.queue).DeviceName)
+			}
+			oldest, newest := p.droppedReadings()
+			assert.Equal(t, tt.wantOldest, oldest)
+			assert.Equal(t, tt.wantNewest, newest)
+		})
+	}
+}
+
+func Test_asyncPublisher_block(t *testing.T) {
+	p := newAsyncPublisher(1, "", &logger.MockLogger{})
+	require.True(t, p.publish(context.Background(), asyncValues("first")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, p.publish(ctx, asyncValues("second")), "a full queue blocks until the service stops")
+
+	out := make(chan *sdkModels.AsyncValues)
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go p.run(runCtx, out)
+	assert.Equal(t, "first", (<-out).DeviceName)
+	assert.True(t, p.publish(context.Background(), asyncValues("third")))
+	assert.Equal(t, "third", (<-out).DeviceName)
+}
+
+// metricsManager holds the metrics registered by the tests
+type metricsManager struct {
+	bootstrapInterfaces.MetricsManager
+	metrics map[string]interface{}
+	tags    map[string]map[string]string
+}
+
+func (m *metricsManager) Register(name string, item interface{}, tags map[string]string) error {
+	m.metrics[name] = item
+	m.tags[name] = tags
+	return nil
+}
+
+func (m *metricsManager) Unregister(name string) {
+	delete(m.metrics, name)
+}
+
+func Test_asyncPublisher_metrics(t *testing.T) {
+	manager := &metricsManager{metrics: map[string]interface{}{}, tags: map[string]map[string]string{}}
+	p := newAsyncPublisher(1, OverflowDropNewest, &logger.MockLogger{})
+	p.registerMetrics(manager)
+
+	p.publish(context.Background(), asyncValues("first"))
+	p.publish(context.Background(), asyncValues("second"))
+
+	require.Contains(t, manager.metrics, asyncReadingsDroppedNewestName)
+	assert.Equal(t, int64(1), manager.metrics[asyncReadingsDroppedNewestName].(gometrics.Counter).Count())
+	assert.Equal(t, int64(0), manager.metrics[asyncReadingsDroppedOldestName].(gometrics.Counter).Count())
+	assert.Equal(t, map[string]string{"policy": OverflowDropNewest}, manager.tags[asyncReadingsDroppedNewestName])
+
+	p.unregisterMetrics(manager)
+	assert.Empty(t, manager.metrics)
+}
+
+func Test_isPowerOfTen(t *testing.T) {
+	for n, want := range map[uint64]bool{1: true, 2: false, 10: true, 11: false, 100: true, 110: false, 1000: true} {
+		assert.Equal(t, want, isPowerOfTen(n), "%d", n)
+	}
+}
@@ -0,0 +1,261 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+)
+
+// connectionOutage tracks the time the subscription client lost its connection to the server
+type connectionOutage struct {
+	since time.Time
+}
+
+// begin records the start of an outage, unless one is already in progress
+func (o *connectionOutage) begin(now time.Time) {
+	if o.since.IsZero() {
+		o.since = now
+	}
+}
+
+// end returns the start of the outage in progress, if any, and ends it
+func (o *connectionOutage) end() (time.Time, bool) {
+	since := o.since
+	o.since = time.Time{}
+	return since, !since.IsZero()
+}
+
+// publishedValue is the last value published for a monitored resource
+type publishedValue struct {
+	timestamp time.Time
+	status    ua.StatusCode
+	value     interface{}
+}
+
+// publishedValues holds the last value published per monitored resource, whose timestamp
+// starts the values to backfill after an outage
+type publishedValues struct {
+	mu     sync.Mutex
+	values map[string]publishedValue
+}
+
+// last returns the timestamp of the last value published for a resource
+func (p *publishedValues) last(deviceName, resourceName string) (time.Time, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	last, ok := p.values[deviceName+"/"+resourceName]
+	return last.timestamp, ok
+}
+
+// advance records a value of a resource, and reports whether it is new: neither older than the
+// last value published nor a copy of it. A value with the timestamp of the last one but another
+// status or value, such as a change of quality, is new. Values without a timestamp are always new.
+func (p *publishedValues) advance(deviceName, resourceName string, dataValue *ua.DataValue) bool {
+	current := publishedValue{timestamp: valueTime(dataValue), status: dataValue.Status}
+	if dataValue.Value != nil {
+		current.value = dataValue.Value.Value()
+	}
+	if current.timestamp.IsZero() {
+		return true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.values == nil {
+		p.values = make(map[string]publishedValue)
+	}
+	key := deviceName + "/" + resourceName
+	if last, ok := p.values[key]; ok {
+		if current.timestamp.Before(last.timestamp) {
+			return false
+		}
+		if current.timestamp.Equal(last.timestamp) && current.status == last.status && reflect.DeepEqual(current.value, last.value) {
+			return false
+		}
+	}
+	p.values[key] = current
+	return true
+}
+
+// valueTime returns the source timestamp of a value, or its server timestamp when the source
+// does not provide one
+func valueTime(dataValue *ua.DataValue) time.Time {
+	if dataValue.SourceTimestamp.IsZero() {
+		return dataValue.ServerTimestamp
+	}
+	return dataValue.SourceTimestamp
+}
+
+// isNewValue reports whether a value of a monitored resource is new, when BackfillOnReconnect is
+// enabled. Values the server republishes after a reconnection, already published or backfilled,
+// are not new.
+func (d *Driver) isNewValue(deviceName, resourceName string, dataValue *ua.DataValue) bool {
+	if !d.serviceConfig.OPCUAServer.BackfillOnReconnect {
+		return true
+	}
+	return d.lastPublished.advance(deviceName, resourceName, dataValue)
+}
+
+// handleConnectionState tracks the outages of the connection of the subscription client. Once
+// it is connected again, the data types of the monitored resources are resolved again and the
+// values missed are published.
+func (d *Driver) handleConnectionState(state opcua.ConnState, outage *connectionOutage, client *opcua.Client, settings deviceSettings, history historyReader, deviceName, resources string) {
+	switch state {
+	case opcua.Disconnected, opcua.Reconnecting:
+		outage.begin(time.Now())
+	case opcua.Connected:
+		d.prepareMonitoredDataTypes(client, deviceName, resources)
+		d.endOutage(outage, client, settings, history, deviceName, resources)
+	}
+}
+
+// prepareMonitoredDataTypes resolves the data types of the monitored resources, dropped when
+// the client reconnects
+func (d *Driver) prepareMonitoredDataTypes(client *opcua.Client, deviceName, resources string) {
+	for _, node := range strings.Split(resources, ",") {
+		deviceResource, ok := d.sdkService.DeviceResource(deviceName, node)
+		if !ok {
+			continue
+		}
+		attributeID, err := getAttributeID(deviceResource.Attributes)
+		if err != nil {
+			continue
+		}
+		id, err := d.resourceNodeID(deviceName, client, deviceResource.Attributes, NODE)
+		if err == nil {
+			err = d.prepareDataType(deviceName, client, deviceResource.Properties.ValueType, attributeID, id)
+		}
+		if err != nil {
+			d.Logger.Warnf("[Incoming listener] Unable to resolve the data type of %s: %v", node, err)
+		}
+	}
+}
+
+// backfillWindow returns the time window to backfill for an outage, limited to the
+// configured BackfillMaxWindow
+func (d *Driver) backfillWindow(since, until time.Time) historyWindow {
+	window := historyWindow{start: since, end: until}
+	if d.serviceConfig.OPCUAServer.BackfillMaxWindow == "" {
+		return window
+	}
+	maxWindow, err := time.ParseDuration(d.serviceConfig.OPCUAServer.BackfillMaxWindow)
+	if err == nil && window.end.Sub(window.start) > maxWindow {
+		window.start = window.end.Add(-maxWindow)
+	}
+	return window
+}
+
+// endOutage publishes the values missed during the outage in progress, if any and if
+// BackfillOnReconnect is enabled. The values of a resource are missed from the last one
+// published, or from the start of the outage when none was.
+func (d *Driver) endOutage(outage *connectionOutage, client *opcua.Client, settings deviceSettings, history historyReader, deviceName, resources string) {
+	since, ok := outage.end()
+	if !ok || !d.serviceConfig.OPCUAServer.BackfillOnReconnect {
+		return
+	}
+	now := time.Now()
+	d.Logger.Infof("[Incoming listener] Backfilling values missed during the outage from %s.", since.Format(time.RFC3339))
+
+	for _, node := range strings.Split(resources, ",") {
+		start := since
+		if last, ok := d.lastPublished.last(deviceName, node); ok && last.Before(now) {
+			start = last
+		}
+		if err := d.backfillResource(client, settings, history, deviceName, node, d.backfillWindow(start, now)); err != nil {
+			d.Logger.Warnf("[Incoming listener] Unable to backfill %s: %v", node, err)
+		}
+	}
+}
+
+// backfillResource reads the values of a monitored resource within a window from the server
+// history, and publishes the values newer than the last one published with their original timestamps
+func (d *Driver) backfillResource(client *opcua.Client, settings deviceSettings, history historyReader, deviceName, resourceName string, window historyWindow) error {
+	deviceResource, ok := d.sdkService.DeviceResource(deviceName, resourceName)
+	if !ok {
+		return fmt.Errorf("unable to find device resource with name %s", resourceName)
+	}
+	attributeID, err := getAttributeID(deviceResource.Attributes)
+	if err != nil {
+		return err
+	}
+	if attributeID != ua.AttributeIDValue {
+		// only values are historized
+		return nil
+	}
+	nodeID, err := d.resourceNodeID(deviceName, client, deviceResource.Attributes, NODE)
+	if err != nil {
+		return err
+	}
+	indexRange, err := getIndexRange(deviceResource.Attributes)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := d.requestContext(settings)
+	defer cancel()
+	node := &ua.HistoryReadValueID{NodeID: nodeID, IndexRange: indexRange, DataEncoding: &ua.QualifiedName{}}
+	values, err := history.readValues(ctx, node, historyDetails(&ua.ReadRawModifiedDetails{
+		StartTime: window.start,
+		EndTime:   window.end,
+	}))
+	if err != nil {
+		return err
+	}
+
+	req := sdkModels.CommandRequest{
+		DeviceResourceName: resourceName,
+		Attributes:         deviceResource.Attributes,
+		Type:               deviceResource.Properties.ValueType,
+	}
+	results := make([]*sdkModels.CommandValue, 0, len(values))
+	for _, value := range values {
+		// the last value published is read again at the start of the window
+		if !d.lastPublished.advance(deviceName, resourceName, value) {
+			continue
+		}
+		result, err := d.newDataValueResult(deviceName, req, value)
+		if err != nil {
+			d.Logger.Debugf("[Incoming listener] Backfilled value of %s ignored: %v", resourceName, err)
+			continue
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		return nil
+	}
+
+	d.Logger.Infof("[Incoming listener] %d values of %s backfilled.", len(results), resourceName)
+	d.publishAsync(&sdkModels.AsyncValues{
+		DeviceName:    deviceName,
+		CommandValues: results,
+	})
+	return nil
+}
+
+// historyReader reads the values of a node from the history of a server
+type historyReader interface {
+	readValues(ctx context.Context, node *ua.HistoryReadValueID, details *ua.ExtensionObject) ([]*ua.DataValue, error)
+}
+
+// clientHistory reads the history of the server a client is connected to
+type clientHistory struct {
+	client *opcua.Client
+}
+
+// readValues reads all values of a node within a window of the server history
+func (h clientHistory) readValues(ctx context.Context, node *ua.HistoryReadValueID, details *ua.ExtensionObject) ([]*ua.DataValue, error) {
+	return historyValues(ctx, h.client, node, details, 0)
+}
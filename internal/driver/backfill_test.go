@@ -0,0 +1,215 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_connectionOutage(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var outage connectionOutage
+
+	_, ok := outage.end()
+	assert.False(t, ok, "no outage in progress")
+
+	outage.begin(start)
+	// the client reports Disconnected, then Reconnecting
+	outage.begin(start.Add(time.Second))
+	since, ok := outage.end()
+	require.True(t, ok)
+	assert.Equal(t, start, since)
+
+	_, ok = outage.end()
+	assert.False(t, ok, "an outage ends once")
+}
+
+func TestDriver_backfillWindow(t *testing.T) {
+	until := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		maxWindow string
+		since     time.Time
+		wantStart time.Time
+	}{
+		{name: "unlimited", since: until.Add(-3 * time.Hour), wantStart: until.Add(-3 * time.Hour)},
+		{name: "within the limit", maxWindow: "1h", since: until.Add(-10 * time.Minute), wantStart: until.Add(-10 * time.Minute)},
+		{name: "limited", maxWindow: "1h", since: until.Add(-3 * time.Hour), wantStart: until.Add(-time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Driver{serviceConfig: &ServiceConfig{OPCUAServer: OPCUAServerConfig{BackfillMaxWindow: tt.maxWindow}}}
+			window := d.backfillWindow(tt.since, until)
+			assert.Equal(t, tt.wantStart, window.start)
+			assert.Equal(t, until, window.end)
+		})
+	}
+}
+
+func Test_publishedValues(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	value := func(v float64, status ua.StatusCode, ts time.Time) *ua.DataValue {
+		return &ua.DataValue{Value: ua.MustVariant(v), Status: status, SourceTimestamp: ts}
+	}
+	var published publishedValues
+
+	_, ok := published.last("Test", "Speed")
+	assert.False(t, ok, "nothing published yet")
+
+	assert.True(t, published.advance("Test", "Speed", value(1.5, ua.StatusOK, ts)))
+	assert.False(t, published.advance("Test", "Speed", value(1.5, ua.StatusOK, ts)), "a value republished is not new")
+	assert.False(t, published.advance("Test", "Speed", value(2.5, ua.StatusOK, ts.Add(-time.Second))), "an older value is not new")
+	assert.True(t, published.advance("Test", "Speed", value(1.5, ua.StatusBad, ts)), "a change of status is new")
+	assert.True(t, published.advance("Test", "Speed", value(2.5, ua.StatusBad, ts)), "a change of value is new")
+	assert.True(t, published.advance("Test", "Torque", value(1.5, ua.StatusOK, ts)), "values are kept per resource")
+	assert.True(t, published.advance("Test", "Speed", value(2.5, ua.StatusBad, time.Time{})), "values without timestamp are always new")
+	assert.True(t, published.advance("Test", "Speed", &ua.DataValue{Status: ua.StatusBad, SourceTimestamp: ts.Add(time.Second)}))
+	assert.False(t, published.advance("Test", "Speed", &ua.DataValue{Status: ua.StatusBad, SourceTimestamp: ts.Add(time.Second)}))
+
+	last, ok := published.last("Test", "Speed")
+	require.True(t, ok)
+	assert.Equal(t, ts.Add(time.Second), last)
+}
+
+// fakeHistory returns the values of its history within the window read, recording the windows
+type fakeHistory struct {
+	values  []*ua.DataValue
+	windows []*ua.ReadRawModifiedDetails
+}
+
+func (h *fakeHistory) readValues(ctx context.Context, node *ua.HistoryReadValueID, details *ua.ExtensionObject) ([]*ua.DataValue, error) {
+	raw := details.Value.(*ua.ReadRawModifiedDetails)
+	h.windows = append(h.windows, raw)
+	var values []*ua.DataValue
+	for _, value := range h.values {
+		if !value.SourceTimestamp.Before(raw.StartTime) && !value.SourceTimestamp.After(raw.EndTime) {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// newBackfillDriver returns a driver backfilling the Speed resource of the Test device
+func newBackfillDriver() (*Driver, chan *sdkModels.AsyncValues) {
+	asyncCh := make(chan *sdkModels.AsyncValues, 10)
+	d := &Driver{
+		Logger:  &logger.MockLogger{},
+		AsyncCh: asyncCh,
+		sdkService: &deviceServiceSDK{resources: map[string]models.DeviceResource{
+			"Speed": {
+				Name:       "Speed",
+				Attributes: map[string]interface{}{NODE: "ns=2;s=Speed"},
+				Properties: models.ResourceProperties{ValueType: common.ValueTypeFloat64},
+			},
+		}},
+		serviceConfig: &ServiceConfig{OPCUAServer: OPCUAServerConfig{BackfillOnReconnect: true, BackfillMaxWindow: "1h"}},
+	}
+	return d, asyncCh
+}
+
+func TestDriver_backfillResource(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	values := []*ua.DataValue{
+		{Value: ua.MustVariant(1.5), Status: ua.StatusOK, SourceTimestamp: ts},
+		{Value: ua.MustVariant(2.5), Status: ua.StatusOK, SourceTimestamp: ts.Add(time.Second)},
+		{Value: ua.MustVariant(3.5), Status: ua.StatusOK, SourceTimestamp: ts.Add(2 * time.Second)},
+	}
+	window := historyWindow{start: ts, end: ts.Add(time.Minute)}
+
+	tests := []struct {
+		name     string
+		resource string
+		last     *ua.DataValue
+		want     []float64
+		wantErr  bool
+	}{
+		{name: "OK - all values of the window", resource: "Speed", want: []float64{1.5, 2.5, 3.5}},
+		{name: "OK - values newer than the last published", resource: "Speed", last: values[0], want: []float64{2.5, 3.5}},
+		{name: "OK - no newer value", resource: "Speed", last: values[2]},
+		{
+			name:     "OK - change of status at the time of the last published",
+			resource: "Speed",
+			last:     &ua.DataValue{Value: ua.MustVariant(1.5), Status: ua.StatusBad, SourceTimestamp: ts},
+			want:     []float64{1.5, 2.5, 3.5},
+		},
+		{name: "NOK - unknown resource", resource: "Torque", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := &fakeHistory{values: values}
+			d, asyncCh := newBackfillDriver()
+			if tt.last != nil {
+				d.lastPublished.advance("Test", "Speed", tt.last)
+			}
+
+			err := d.backfillResource(nil, deviceSettings{RequestTimeout: time.Second}, history, "Test", tt.resource, window)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, history.windows, 1)
+			assert.Equal(t, window.start, history.windows[0].StartTime)
+			assert.Equal(t, window.end, history.windows[0].EndTime)
+			if tt.want == nil {
+				assert.Empty(t, asyncCh)
+				return
+			}
+			require.Len(t, asyncCh, 1)
+			values := <-asyncCh
+			var got []float64
+			for _, value := range values.CommandValues {
+				got = append(got, value.Value.(float64))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDriver_handleConnectionState(t *testing.T) {
+	last := time.Now().Add(-time.Minute)
+	history := &fakeHistory{values: []*ua.DataValue{
+		{Value: ua.MustVariant(1.5), Status: ua.StatusOK, SourceTimestamp: last},
+		{Value: ua.MustVariant(2.5), Status: ua.StatusOK, SourceTimestamp: last.Add(time.Second)},
+	}}
+	d, asyncCh := newBackfillDriver()
+	settings := deviceSettings{RequestTimeout: time.Second}
+	require.True(t, d.isNewValue("Test", "Speed", history.values[0]))
+
+	var outage connectionOutage
+	d.handleConnectionState(opcua.Connected, &outage, nil, settings, history, "Test", "Speed")
+	assert.Empty(t, history.windows, "no backfill without an outage")
+
+	d.handleConnectionState(opcua.Disconnected, &outage, nil, settings, history, "Test", "Speed")
+	d.handleConnectionState(opcua.Reconnecting, &outage, nil, settings, history, "Test", "Speed")
+	d.handleConnectionState(opcua.Connected, &outage, nil, settings, history, "Test", "Speed")
+
+	require.Len(t, history.windows, 1, "one backfill per outage")
+	assert.Equal(t, last, history.windows[0].StartTime, "the backfill starts at the last value published")
+	require.Len(t, asyncCh, 1)
+	values := <-asyncCh
+	require.Len(t, values.CommandValues, 1)
+	assert.Equal(t, 2.5, values.CommandValues[0].Value)
+
+	assert.False(t, d.isNewValue("Test", "Speed", history.values[1]), "a value republished after the backfill is not new")
+	assert.True(t, d.isNewValue("Test", "Speed", &ua.DataValue{Value: ua.MustVariant(2.5), Status: ua.StatusBad, SourceTimestamp: last.Add(time.Second)}),
+		"a change of quality is published")
+
+	d.handleConnectionState(opcua.Connected, &outage, nil, settings, history, "Test", "Speed")
+	assert.Len(t, history.windows, 1, "an outage is backfilled once")
+}
@@ -0,0 +1,321 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/id"
+	"github.com/gopcua/opcua/ua"
+)
+
+const (
+	// browsePathPrefix starts node attributes holding a browse path instead of a node id
+	browsePathPrefix = "/"
+	// namespaceURIPrefix starts node ids referencing their namespace by URI instead of index
+	namespaceURIPrefix = "nsu="
+)
+
+// nodeIDCache holds the node ids resolved from browse paths and namespace URIs per device,
+// along with the client which resolved them
+type nodeIDCache struct {
+	mu    sync.Mutex
+	nodes map[string]resolvedNodeID
+}
+
+type resolvedNodeID struct {
+	client *opcua.Client
+	id     *ua.NodeID
+}
+
+func (c *nodeIDCache) get(key string, client *opcua.Client) (*ua.NodeID, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	node, ok := c.nodes[key]
+	if !ok || (client != nil && node.client != client) {
+		return nil, false
+	}
+	return node.id, true
+}
+
+func (c *nodeIDCache) store(key string, client *opcua.Client, id *ua.NodeID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nodes == nil {
+		c.nodes = make(map[string]resolvedNodeID)
+	}
+	c.nodes[key] = resolvedNodeID{client: client, id: id}
+}
+
+// forgetClient drops the node ids resolved by a client
+func (c *nodeIDCache) forgetClient(client *opcua.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, node := range c.nodes {
+		if node.client == client {
+			delete(c.nodes, key)
+		}
+	}
+}
+
+// forgetNodes drops the node ids resolved and registered by a client, and the data type
+// definitions it fetched. The registered nodes are released while the client is connected.
+func (d *Driver) forgetNodes(client *opcua.Client) {
+	d.nodeIDs.forgetClient(client)
+	d.unregisterNodes(client, d.registeredNodes.takeClient(client))
+	d.dataTypes.forgetClient(client)
+}
+
+// clientCloseTimeout bounds the time taken to close a client
+const clientCloseTimeout = 5 * time.Second
+
+// clientWatches holds the channels stopping the watch of the connection state per client
+type clientWatches struct {
+	mu   sync.Mutex
+	done map[*opcua.Client]chan struct{}
+}
+
+func (w *clientWatches) start(client *opcua.Client) <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done == nil {
+		w.done = make(map[*opcua.Client]chan struct{})
+	}
+	done := make(chan struct{})
+	w.done[client] = done
+	return done
+}
+
+func (w *clientWatches) stop(client *opcua.Client) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if done, ok := w.done[client]; ok {
+		close(done)
+		delete(w.done, client)
+	}
+}
+
+// watchConnection drops the node ids resolved and registered by a client when it reconnects,
+// as the server may have been restarted with other node ids in the meantime. States are
+// forwarded to the listener, if any, without waiting for it. The watch ends when done is closed.
+func (d *Driver) watchConnection(client *opcua.Client, states <-chan opcua.ConnState, listener chan<- opcua.ConnState, done <-chan struct{}) {
+	for {
+		var state opcua.ConnState
+		select {
+		case <-done:
+			return
+		case state = <-states:
+		}
+		switch state {
+		case opcua.Reconnecting:
+			d.forgetNodes(client)
+		case opcua.Closed:
+			d.forgetNodes(client)
+		}
+		if listener != nil {
+			select {
+			case listener <- state:
+			default:
+				d.Logger.Debugf("Connection state %v not forwarded", state)
+			}
+		}
+	}
+}
+
+// newWatchedClient creates a client whose connection state is watched by the driver,
+// and optionally by a listener, until it is released with releaseClient
+func (d *Driver) newWatchedClient(endpoint string, listener chan<- opcua.ConnState, opts ...opcua.Option) (*opcua.Client, error) {
+	// buffered, so that state changes do not block the client
+	states := make(chan opcua.ConnState, 8)
+	client, err := opcua.NewClient(endpoint, append(opts, opcua.StateChangedCh(states))...)
+	if err != nil {
+		return nil, err
+	}
+	go d.watchConnection(client, states, listener, d.watches.start(client))
+	return client, nil
+}
+
+// releaseClient closes a client, connected or not, and stops watching its connection state.
+// The client is closed with a context of its own, as the context of its user may be cancelled.
+func (d *Driver) releaseClient(client *opcua.Client) {
+	// the nodes are forgotten first, so that the registered ones are released in the session
+	d.forgetNodes(client)
+	ctx, cancel := context.WithTimeout(context.Background(), clientCloseTimeout)
+	defer cancel()
+	_ = client.Close(ctx)
+	d.watches.stop(client)
+}
+
+// nodeAttribute returns the node id or browse path of a resource attribute. The browsePath
+// attribute may be used in place of nodeId.
+func nodeAttribute(attrs map[string]interface{}, id string) (string, error) {
+	if _, ok := attrs[id]; ok {
+		return getNodeID(attrs, id)
+	}
+	if id == NODE {
+		if value, ok := attrs[BROWSEPATH]; ok {
+			path := fmt.Sprintf("%v", value)
+			if !strings.HasPrefix(path, browsePathPrefix) {
+				return "", fmt.Errorf("%s %s does not start with %s", BROWSEPATH, path, browsePathPrefix)
+			}
+			return path, nil
+		}
+		return "", fmt.Errorf("attribute %s or %s does not exist", NODE, BROWSEPATH)
+	}
+	return "", fmt.Errorf("attribute %s does not exist", id)
+}
+
+// isResolvedNode reports whether a node attribute is resolved against the server
+func isResolvedNode(node string) bool {
+	return strings.HasPrefix(node, browsePathPrefix) || strings.HasPrefix(node, namespaceURIPrefix)
+}
+
+// checkNodeAttribute checks the syntax of the node id or browse path of a resource attribute
+func checkNodeAttribute(attrs map[string]interface{}, id string) error {
+	node, err := nodeAttribute(attrs, id)
+	if err != nil {
+		return err
+	}
+	switch {
+	case strings.HasPrefix(node, browsePathPrefix):
+		_, err = parseBrowsePath(node)
+	case strings.HasPrefix(node, namespaceURIPrefix):
+		// any namespace array holding the URI will do for the syntax
+		uri, _, _ := strings.Cut(strings.TrimPrefix(node, namespaceURIPrefix), ";")
+		_, err = ua.ParseExpandedNodeID(node, []string{uri})
+	default:
+		_, err = ua.ParseNodeID(node)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s %s: %v", id, node, err)
+	}
+	return nil
+}
+
+// resourceNodeID returns the node of a resource attribute. Browse paths are resolved
+// on the server once per client and cached. Namespace URIs are resolved with the
+// NamespaceArray of the current session of the client.
+func (d *Driver) resourceNodeID(deviceName string, client *opcua.Client, attrs map[string]interface{}, id string) (*ua.NodeID, error) {
+	node, err := nodeAttribute(attrs, id)
+	if err != nil {
+		return nil, err
+	}
+	key := deviceName + "/" + node
+
+	if strings.HasPrefix(node, namespaceURIPrefix) {
+		nodeID, err := namespaceNodeID(d.serviceContext(), client, node)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s: %v", id, node, err)
+		}
+		// cached for cachedNodeID only, the namespace array may change
+		d.nodeIDs.store(key, client, nodeID)
+		return nodeID, nil
+	}
+	if !strings.HasPrefix(node, browsePathPrefix) {
+		nodeID, err := ua.ParseNodeID(node)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s: %v", id, node, err)
+		}
+		return nodeID, nil
+	}
+
+	if nodeID, ok := d.nodeIDs.get(key, client); ok {
+		return nodeID, nil
+	}
+	nodeID, err := translateBrowsePath(d.serviceContext(), client, node)
+	if err != nil {
+		return nil, err
+	}
+	d.nodeIDs.store(key, client, nodeID)
+	return nodeID, nil
+}
+
+// cachedNodeID returns the node of a resource attribute without contacting the server,
+// using the last resolution of browse paths and namespace URIs
+func (d *Driver) cachedNodeID(deviceName string, attrs map[string]interface{}, id string) (*ua.NodeID, bool) {
+	node, err := nodeAttribute(attrs, id)
+	if err != nil {
+		return nil, false
+	}
+	if isResolvedNode(node) {
+		return d.nodeIDs.get(deviceName+"/"+node, nil)
+	}
+	nodeID, err := ua.ParseNodeID(node)
+	if err != nil {
+		return nil, false
+	}
+	return nodeID, true
+}
+
+// namespaceNodeID resolves a node id holding a namespace URI, such as
+// nsu=http://example.com/Machine;s=Speed, with the NamespaceArray of the server
+func namespaceNodeID(ctx context.Context, client *opcua.Client, node string) (*ua.NodeID, error) {
+	namespaces := client.Namespaces()
+	if len(namespaces) == 0 {
+		if err := client.UpdateNamespaces(ctx); err != nil {
+			return nil, fmt.Errorf("unable to read the NamespaceArray: %v", err)
+		}
+		namespaces = client.Namespaces()
+	}
+	expanded, err := ua.ParseExpandedNodeID(node, namespaces)
+	if err != nil {
+		return nil, err
+	}
+	return expanded.NodeID, nil
+}
+
+// checkNodeStatus resolves the nodes of a client again when the server reports an unknown
+// node, as the NamespaceArray or the address space of the server may have changed
+func (d *Driver) checkNodeStatus(client *opcua.Client, status ua.StatusCode) {
+	if status != ua.StatusBadNodeIDUnknown && status != ua.StatusBadMethodInvalid {
+		return
+	}
+	d.forgetNodes(client)
+	if err := client.UpdateNamespaces(d.serviceContext()); err != nil {
+		d.Logger.Debugf("Unable to read the NamespaceArray: %v", err)
+	}
+}
+
+// translateBrowsePath resolves a browse path starting at the Root folder
+func translateBrowsePath(ctx context.Context, client *opcua.Client, path string) (*ua.NodeID, error) {
+	names, err := parseBrowsePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %s: %v", BROWSEPATH, path, err)
+	}
+
+	nodeID, err := client.Node(ua.NewNumericNodeID(0, id.RootFolder)).TranslateBrowsePathsToNodeIDs(ctx, names)
+	if err != nil {
+		if err == ua.StatusBadNoMatch {
+			return nil, fmt.Errorf("browse path %s not found", path)
+		}
+		return nil, fmt.Errorf("unable to resolve browse path %s: %v", path, err)
+	}
+	return nodeID, nil
+}
+
+// parseBrowsePath splits a browse path such as /Objects/2:Machine/2:Speed into the
+// browse names of its elements, namespace 0 being the default
+func parseBrowsePath(path string) ([]*ua.QualifiedName, error) {
+	elements := strings.Split(strings.TrimPrefix(path, browsePathPrefix), "/")
+	names := make([]*ua.QualifiedName, len(elements))
+	for i, element := range elements {
+		if element == "" {
+			return nil, fmt.Errorf("empty element %d", i+1)
+		}
+		name, err := parseQualifiedName(element)
+		if err != nil {
+			return nil, err
+		}
+		names[i] = name
+	}
+	return names, nil
+}
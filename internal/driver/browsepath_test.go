@@ -0,0 +1,170 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/clients/logger"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseBrowsePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    []*ua.QualifiedName
+		wantErr bool
+	}{
+		{
+			name: "OK - namespaced elements",
+			path: "/Objects/2:Machine/2:Speed",
+			want: []*ua.QualifiedName{{Name: "Objects"}, {NamespaceIndex: 2, Name: "Machine"}, {NamespaceIndex: 2, Name: "Speed"}},
+		},
+		{
+			name: "OK - name with a colon",
+			path: "/Objects/Line:A",
+			want: []*ua.QualifiedName{{Name: "Objects"}, {Name: "Line:A"}},
+		},
+		{name: "NOK - empty path", path: "/", wantErr: true},
+		{name: "NOK - empty element", path: "/Objects//2:Speed", wantErr: true},
+		{name: "NOK - trailing slash", path: "/Objects/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBrowsePath(tt.path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_checkNodeAttribute(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   map[string]interface{}
+		id      string
+		wantErr bool
+	}{
+		{name: "OK - node id", attrs: map[string]interface{}{NODE: "ns=2;s=Speed"}, id: NODE},
+		{name: "OK - browse path", attrs: map[string]interface{}{BROWSEPATH: "/Objects/2:Machine/2:Speed"}, id: NODE},
+		{name: "OK - browse path in methodId", attrs: map[string]interface{}{METHOD: "/Objects/2:Machine/2:Start"}, id: METHOD},
+		{name: "NOK - browse path not absolute", attrs: map[string]interface{}{BROWSEPATH: "Objects/2:Machine"}, id: NODE, wantErr: true},
+		{name: "NOK - invalid browse path", attrs: map[string]interface{}{BROWSEPATH: "/Objects//2:Speed"}, id: NODE, wantErr: true},
+		{name: "NOK - invalid node id", attrs: map[string]interface{}{OBJECT: "ns=x;i=1"}, id: OBJECT, wantErr: true},
+		{name: "NOK - no node", attrs: map[string]interface{}{}, id: NODE, wantErr: true},
+		{name: "OK - namespace URI", attrs: map[string]interface{}{NODE: "nsu=http://example.com/Machine;s=Speed"}, id: NODE},
+		{name: "NOK - invalid namespace URI node id", attrs: map[string]interface{}{NODE: "nsu=http://example.com/Machine;i=Speed"}, id: NODE, wantErr: true},
+		{name: "NOK - browsePath only replaces nodeId", attrs: map[string]interface{}{BROWSEPATH: "/Objects/2:Machine"}, id: OBJECT, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkNodeAttribute(tt.attrs, tt.id)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDriver_cachedNodeID(t *testing.T) {
+	client := &opcua.Client{}
+	other := &opcua.Client{}
+	d := &Driver{}
+	d.nodeIDs.store("Test//Objects/2:Machine/2:Speed", client, ua.NewStringNodeID(2, "Speed"))
+
+	id, ok := d.cachedNodeID("Test", map[string]interface{}{BROWSEPATH: "/Objects/2:Machine/2:Speed"}, NODE)
+	require.True(t, ok)
+	assert.Equal(t, "ns=2;s=Speed", id.String())
+
+	id, ok = d.cachedNodeID("Test", map[string]interface{}{NODE: "ns=2;s=Counter"}, NODE)
+	require.True(t, ok)
+	assert.Equal(t, "ns=2;s=Counter", id.String())
+
+	_, ok = d.cachedNodeID("Other", map[string]interface{}{BROWSEPATH: "/Objects/2:Machine/2:Speed"}, NODE)
+	assert.False(t, ok, "browse paths are resolved per device")
+
+	_, ok = d.nodeIDs.get("Test//Objects/2:Machine/2:Speed", other)
+	assert.False(t, ok, "browse paths are resolved per client")
+
+	d.nodeIDs.forgetClient(client)
+	_, ok = d.cachedNodeID("Test", map[string]interface{}{BROWSEPATH: "/Objects/2:Machine/2:Speed"}, NODE)
+	assert.False(t, ok, "browse paths are resolved again after a reconnect")
+}
+
+func TestDriver_checkNodeStatus(t *testing.T) {
+	client, err := opcua.NewClient("opc.tcp://localhost:4840")
+	require.NoError(t, err)
+	attrs := map[string]interface{}{NODE: "nsu=http://example.com/Machine;s=Speed"}
+
+	tests := []struct {
+		name       string
+		status     ua.StatusCode
+		wantCached bool
+	}{
+		{"good status", ua.StatusOK, true},
+		{"bad value", ua.StatusBadOutOfRange, true},
+		{"unknown node", ua.StatusBadNodeIDUnknown, false},
+		{"unknown method", ua.StatusBadMethodInvalid, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Driver{Logger: &logger.MockLogger{}}
+			d.nodeIDs.store("Test/nsu=http://example.com/Machine;s=Speed", client, ua.NewStringNodeID(2, "Speed"))
+			d.checkNodeStatus(client, tt.status)
+			_, ok := d.cachedNodeID("Test", attrs, NODE)
+			assert.Equal(t, tt.wantCached, ok)
+		})
+	}
+}
+
+func TestDriver_watchConnection(t *testing.T) {
+	client := &opcua.Client{}
+	d := &Driver{Logger: &logger.MockLogger{}}
+	d.nodeIDs.store("Test//Objects/2:Machine/2:Speed", client, ua.NewStringNodeID(2, "Speed"))
+
+	states := make(chan opcua.ConnState)
+	listener := make(chan opcua.ConnState, 1)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		d.watchConnection(client, states, listener, done)
+		close(stopped)
+	}()
+	states <- opcua.Reconnecting
+	states <- opcua.Connected
+	close(done)
+	<-stopped
+
+	_, ok := d.nodeIDs.get("Test//Objects/2:Machine/2:Speed", client)
+	assert.False(t, ok, "node ids are resolved again after a reconnect")
+	require.Len(t, listener, 1, "states are dropped when the listener is busy")
+	assert.Equal(t, opcua.Reconnecting, <-listener)
+}
+
+func TestDriver_releaseClient(t *testing.T) {
+	d := &Driver{Logger: &logger.MockLogger{}}
+	client, err := d.newWatchedClient("opc.tcp://localhost:4840", nil)
+	require.NoError(t, err)
+	d.nodeIDs.store("Test//Objects/2:Machine/2:Speed", client, ua.NewStringNodeID(2, "Speed"))
+
+	// a client which never connected is released as well
+	d.releaseClient(client)
+
+	assert.Empty(t, d.watches.done, "the watch of a released client is stopped")
+	_, ok := d.nodeIDs.get("Test//Objects/2:Machine/2:Speed", client)
+	assert.False(t, ok)
+}
@@ -11,9 +11,11 @@ package driver
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/errors"
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/spf13/cast"
 )
 
 // ServiceConfig configuration struct
@@ -41,7 +43,31 @@ type OPCUAServerConfig struct {
 	Mode       string
 	CertFile   string
 	KeyFile    string
-	Writable   WritableInfo
+	// TimestampSource selects the reading origin: Source, Server or Local (defaults to Source)
+	TimestampSource string
+	// UncertainPolicy handles Uncertain values: Publish, Drop or Replace (defaults to Publish)
+	UncertainPolicy string
+	// BadPolicy handles Bad values: Publish, Drop or Replace (defaults to Drop)
+	BadPolicy string
+	// DataTransform applies the mask, shift, base, scale and offset of device resources in the
+	// driver, before range checks. Disable Device.DataTransform to not transform values twice.
+	DataTransform bool
+	// RegisterNodesInterval registers the nodes of resources read by AutoEvents at this
+	// interval or more often with the RegisterNodes service, e.g. 1s (disabled when empty)
+	RegisterNodesInterval string
+	// BackfillOnReconnect reads the values of monitored resources missed while the subscription
+	// was disconnected from the server history, and publishes them before the live values
+	BackfillOnReconnect bool
+	// BackfillMaxWindow limits the backfilled time window to the end of an outage, e.g. 1h (unlimited when empty)
+	BackfillMaxWindow string
+	// AsyncBufferSize is the number of async values queued for the SDK (defaults to 100)
+	AsyncBufferSize int
+	// AsyncOverflowPolicy handles a full queue: Block, DropOldest or DropNewest (defaults to Block)
+	AsyncOverflowPolicy string
+	// NotificationGrouping groups the values of a subscription notification into events:
+	// None, Device or Command (defaults to None, one event per value)
+	NotificationGrouping string
+	Writable             WritableInfo
 }
 
 // WritableInfo configuration data that can be written without restarting the service
@@ -62,6 +88,30 @@ var modes map[string]int = map[string]int{
 	"SignAndEncrypt": 3,
 }
 
+var timestampSources map[string]int = map[string]int{
+	TimestampSource: 1,
+	TimestampServer: 2,
+	TimestampLocal:  3,
+}
+
+var qualityPolicies map[string]int = map[string]int{
+	QualityPublish: 1,
+	QualityDrop:    2,
+	QualityReplace: 3,
+}
+
+var notificationGroupings map[string]int = map[string]int{
+	GroupingNone:    1,
+	GroupingDevice:  2,
+	GroupingCommand: 3,
+}
+
+var overflowPolicies map[string]int = map[string]int{
+	OverflowBlock:      1,
+	OverflowDropOldest: 2,
+	OverflowDropNewest: 3,
+}
+
 // Validate ensures your custom configuration has proper values.
 func (info *OPCUAServerConfig) Validate() errors.EdgeX {
 	if info.DeviceName == "" {
@@ -74,6 +124,34 @@ func (info *OPCUAServerConfig) Validate() errors.EdgeX {
 	if _, ok := modes[info.Mode]; !ok {
 		return errors.NewCommonEdgeX(errors.KindContractInvalid, "OPCUAServerInfo.Mode configuration setting mismatch", nil)
 	}
+	if _, ok := timestampSources[info.TimestampSource]; info.TimestampSource != "" && !ok {
+		return errors.NewCommonEdgeX(errors.KindContractInvalid, "OPCUAServerInfo.TimestampSource configuration setting mismatch", nil)
+	}
+	if _, ok := qualityPolicies[info.UncertainPolicy]; info.UncertainPolicy != "" && !ok {
+		return errors.NewCommonEdgeX(errors.KindContractInvalid, "OPCUAServerInfo.UncertainPolicy configuration setting mismatch", nil)
+	}
+	if _, ok := qualityPolicies[info.BadPolicy]; info.BadPolicy != "" && !ok {
+		return errors.NewCommonEdgeX(errors.KindContractInvalid, "OPCUAServerInfo.BadPolicy configuration setting mismatch", nil)
+	}
+	if info.RegisterNodesInterval != "" {
+		if _, err := time.ParseDuration(info.RegisterNodesInterval); err != nil {
+			return errors.NewCommonEdgeX(errors.KindContractInvalid, "OPCUAServerInfo.RegisterNodesInterval configuration setting is not a duration", err)
+		}
+	}
+	if info.BackfillMaxWindow != "" {
+		if _, err := time.ParseDuration(info.BackfillMaxWindow); err != nil {
+			return errors.NewCommonEdgeX(errors.KindContractInvalid, "OPCUAServerInfo.BackfillMaxWindow configuration setting is not a duration", err)
+		}
+	}
+	if info.AsyncBufferSize < 0 {
+		return errors.NewCommonEdgeX(errors.KindContractInvalid, "OPCUAServerInfo.AsyncBufferSize configuration setting cannot be negative", nil)
+	}
+	if _, ok := overflowPolicies[info.AsyncOverflowPolicy]; info.AsyncOverflowPolicy != "" && !ok {
+		return errors.NewCommonEdgeX(errors.KindContractInvalid, "OPCUAServerInfo.AsyncOverflowPolicy configuration setting mismatch", nil)
+	}
+	if _, ok := notificationGroupings[info.NotificationGrouping]; info.NotificationGrouping != "" && !ok {
+		return errors.NewCommonEdgeX(errors.KindContractInvalid, "OPCUAServerInfo.NotificationGrouping configuration setting mismatch", nil)
+	}
 	if info.Mode != "None" || info.Policy != "None" {
 		if info.CertFile == "" {
 			return errors.NewCommonEdgeX(errors.KindContractInvalid, "OPCUAServerInfo.CertFile configuration setting cannot be blank when a security mode or policy is set", nil)
@@ -102,3 +180,69 @@ func FetchEndpoint(protocols map[string]models.ProtocolProperties) (string, erro
 	}
 	return endpointString, nil
 }
+
+const (
+	// defaultMaxAge is the maximum age of values read from the server cache, in milliseconds
+	defaultMaxAge = 2000
+	// defaultRequestTimeout bounds requests to the server, as gopcua does by default
+	defaultRequestTimeout = 10 * time.Second
+)
+
+// deviceSettings holds the optional protocol properties of a device
+type deviceSettings struct {
+	// MaxAge of values read from the server cache in milliseconds
+	MaxAge float64
+	// RequestTimeout bounds connecting to the server and every request
+	RequestTimeout time.Duration
+	// SessionTimeout requested for the session, zero for the client default
+	SessionTimeout time.Duration
+}
+
+// fetchDeviceSettings returns the MaxAge, RequestTimeout and SessionTimeout protocol properties
+func fetchDeviceSettings(protocols map[string]models.ProtocolProperties) (deviceSettings, errors.EdgeX) {
+	settings := deviceSettings{MaxAge: defaultMaxAge, RequestTimeout: defaultRequestTimeout}
+	properties := protocols[Protocol]
+
+	if value, ok := properties[MaxAge]; ok {
+		maxAge, err := parseMilliseconds(value)
+		if err != nil {
+			return settings, errors.NewCommonEdgeX(errors.KindContractInvalid, fmt.Sprintf("invalid '%s' in the '%s' protocol properties", MaxAge, Protocol), err)
+		}
+		settings.MaxAge = float64(maxAge) / float64(time.Millisecond)
+	}
+	if value, ok := properties[RequestTimeout]; ok {
+		timeout, err := parseMilliseconds(value)
+		if err != nil || timeout <= 0 {
+			return settings, errors.NewCommonEdgeX(errors.KindContractInvalid, fmt.Sprintf("invalid '%s' in the '%s' protocol properties", RequestTimeout, Protocol), err)
+		}
+		settings.RequestTimeout = timeout
+	}
+	if value, ok := properties[SessionTimeout]; ok {
+		timeout, err := parseMilliseconds(value)
+		if err != nil {
+			return settings, errors.NewCommonEdgeX(errors.KindContractInvalid, fmt.Sprintf("invalid '%s' in the '%s' protocol properties", SessionTimeout, Protocol), err)
+		}
+		settings.SessionTimeout = timeout
+	}
+	return settings, nil
+}
+
+// parseMilliseconds parses a duration such as 1.5s, or a number of milliseconds
+func parseMilliseconds(value interface{}) (time.Duration, error) {
+	if text, ok := value.(string); ok {
+		if duration, err := time.ParseDuration(text); err == nil {
+			if duration < 0 {
+				return 0, fmt.Errorf("negative duration %s", text)
+			}
+			return duration, nil
+		}
+	}
+	milliseconds, err := cast.ToFloat64E(value)
+	if err != nil {
+		return 0, err
+	}
+	if milliseconds < 0 {
+		return 0, fmt.Errorf("negative duration %v", value)
+	}
+	return time.Duration(milliseconds * float64(time.Millisecond)), nil
+}
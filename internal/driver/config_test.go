@@ -9,18 +9,27 @@ package driver
 
 import (
 	"testing"
+	"time"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
 )
 
 func TestOPCUAServerConfig_Validate(t *testing.T) {
 	type fields struct {
-		DeviceName string
-		Policy     string
-		Mode       string
-		CertFile   string
-		KeyFile    string
-		Writable   WritableInfo
+		DeviceName      string
+		Policy          string
+		Mode            string
+		CertFile        string
+		KeyFile         string
+		TimestampSource string
+		UncertainPolicy string
+		BadPolicy       string
+		RegisterNodes   string
+		BackfillWindow  string
+		AsyncBuffer     int
+		AsyncOverflow   string
+		Grouping        string
+		Writable        WritableInfo
 	}
 	tests := []struct {
 		name      string
@@ -52,6 +61,76 @@ func TestOPCUAServerConfig_Validate(t *testing.T) {
 			fields:    fields{DeviceName: "Test", Policy: "Basic256", Mode: "Sign", CertFile: "path/to/cert"},
 			wantError: true,
 		},
+		{
+			name:      "NOK - timestamp source mismatch",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", TimestampSource: "Device"},
+			wantError: true,
+		},
+		{
+			name:      "NOK - uncertain policy mismatch",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", UncertainPolicy: "Keep"},
+			wantError: true,
+		},
+		{
+			name:      "NOK - bad policy mismatch",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", BadPolicy: "Keep"},
+			wantError: true,
+		},
+		{
+			name:      "NOK - register nodes interval is not a duration",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", RegisterNodes: "often"},
+			wantError: true,
+		},
+		{
+			name:      "OK - valid configuration with register nodes interval",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", RegisterNodes: "1s"},
+			wantError: false,
+		},
+		{
+			name:      "NOK - backfill window is not a duration",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", BackfillWindow: "1 hour"},
+			wantError: true,
+		},
+		{
+			name:      "OK - valid configuration with backfill window",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", BackfillWindow: "1h"},
+			wantError: false,
+		},
+		{
+			name:      "NOK - negative async buffer size",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", AsyncBuffer: -1},
+			wantError: true,
+		},
+		{
+			name:      "NOK - async overflow policy mismatch",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", AsyncOverflow: "Discard"},
+			wantError: true,
+		},
+		{
+			name:      "OK - valid configuration with async overflow policy",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", AsyncBuffer: 10, AsyncOverflow: OverflowDropOldest},
+			wantError: false,
+		},
+		{
+			name:      "NOK - notification grouping mismatch",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", Grouping: "Resource"},
+			wantError: true,
+		},
+		{
+			name:      "OK - valid configuration with notification grouping",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", Grouping: GroupingCommand},
+			wantError: false,
+		},
+		{
+			name:      "OK - valid configuration with quality policies",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", UncertainPolicy: QualityDrop, BadPolicy: QualityReplace},
+			wantError: false,
+		},
+		{
+			name:      "OK - valid configuration with server timestamp source",
+			fields:    fields{DeviceName: "Test", Policy: "None", Mode: "None", TimestampSource: TimestampServer},
+			wantError: false,
+		},
 		{
 			name:      "OK - valid configuration with policy and mode",
 			fields:    fields{DeviceName: "Test", Policy: "Basic256", Mode: "Sign", CertFile: "path/to/cert", KeyFile: "path/to/key"},
@@ -66,12 +145,20 @@ func TestOPCUAServerConfig_Validate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			info := &OPCUAServerConfig{
-				DeviceName: tt.fields.DeviceName,
-				Policy:     tt.fields.Policy,
-				Mode:       tt.fields.Mode,
-				CertFile:   tt.fields.CertFile,
-				KeyFile:    tt.fields.KeyFile,
-				Writable:   tt.fields.Writable,
+				DeviceName:            tt.fields.DeviceName,
+				Policy:                tt.fields.Policy,
+				Mode:                  tt.fields.Mode,
+				CertFile:              tt.fields.CertFile,
+				KeyFile:               tt.fields.KeyFile,
+				TimestampSource:       tt.fields.TimestampSource,
+				UncertainPolicy:       tt.fields.UncertainPolicy,
+				BadPolicy:             tt.fields.BadPolicy,
+				RegisterNodesInterval: tt.fields.RegisterNodes,
+				BackfillMaxWindow:     tt.fields.BackfillWindow,
+				AsyncBufferSize:       tt.fields.AsyncBuffer,
+				AsyncOverflowPolicy:   tt.fields.AsyncOverflow,
+				NotificationGrouping:  tt.fields.Grouping,
+				Writable:              tt.fields.Writable,
 			}
 			if got := info.Validate(); got != nil && !tt.wantError || got == nil && tt.wantError {
 				t.Errorf("OPCUAServerConfig.Validate() = %v, wantError %v", got, tt.wantError)
@@ -127,6 +214,58 @@ func Test_FetchEndpoint(t *testing.T) {
 	}
 }
 
+func Test_fetchDeviceSettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties models.ProtocolProperties
+		want       deviceSettings
+		wantError  bool
+	}{
+		{
+			name:       "OK - defaults",
+			properties: models.ProtocolProperties{Endpoint: "opc://test-endpoint"},
+			want:       deviceSettings{MaxAge: 2000, RequestTimeout: 10 * time.Second},
+		},
+		{
+			name:       "OK - durations",
+			properties: models.ProtocolProperties{MaxAge: "500ms", RequestTimeout: "3s", SessionTimeout: "5m"},
+			want:       deviceSettings{MaxAge: 500, RequestTimeout: 3 * time.Second, SessionTimeout: 5 * time.Minute},
+		},
+		{
+			name:       "OK - milliseconds",
+			properties: models.ProtocolProperties{MaxAge: 0, RequestTimeout: "1500", SessionTimeout: 60000.0},
+			want:       deviceSettings{MaxAge: 0, RequestTimeout: 1500 * time.Millisecond, SessionTimeout: time.Minute},
+		},
+		{
+			name:       "NOK - invalid max age",
+			properties: models.ProtocolProperties{MaxAge: "fresh"},
+			wantError:  true,
+		},
+		{
+			name:       "NOK - negative session timeout",
+			properties: models.ProtocolProperties{SessionTimeout: "-1s"},
+			wantError:  true,
+		},
+		{
+			name:       "NOK - zero request timeout",
+			properties: models.ProtocolProperties{RequestTimeout: 0},
+			wantError:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetchDeviceSettings(map[string]models.ProtocolProperties{Protocol: tt.properties})
+			if (err != nil) != tt.wantError {
+				t.Errorf("fetchDeviceSettings() error = %v, wantError %v", err, tt.wantError)
+				return
+			}
+			if !tt.wantError && got != tt.want {
+				t.Errorf("fetchDeviceSettings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestServiceConfig_UpdateFromRaw(t *testing.T) {
 	type fields struct {
 		OPCUAServer OPCUAServerConfig
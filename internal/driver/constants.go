@@ -24,11 +24,92 @@ const (
 	METHOD = "methodId"
 	// INPUTMAP attribute
 	INPUTMAP = "inputMap"
+	// ATTRIBUTE node attribute to access, defaults to Value
+	ATTRIBUTE = "attributeId"
+	// INDEXRANGE attribute selecting elements of an array node
+	INDEXRANGE = "indexRange"
+	// MAXAGE attribute overriding the MaxAge protocol property for a resource
+	MAXAGE = "maxAge"
+	// BROWSEPATH attribute addressing a node by its browse path, in place of nodeId
+	BROWSEPATH = "browsePath"
+	// HISTORY attribute reading the history of the node of a resource instead of its value
+	HISTORY = "history"
+	// AGGREGATE attribute naming the aggregate of a Processed history resource
+	AGGREGATE = "aggregate"
+	// PROCESSINGINTERVAL attribute giving the interval of the aggregates of a Processed history resource
+	PROCESSINGINTERVAL = "processingInterval"
+	// EVENTFIELDS attribute selecting the event fields of an Events history resource
+	EVENTFIELDS = "eventFields"
+	// EVENTTYPE attribute restricting an Events history resource to an event type and its subtypes
+	EVENTTYPE = "eventType"
+	// MINSEVERITY attribute restricting an Events history resource to a minimum severity
+	MINSEVERITY = "minSeverity"
+	// ENCODING attribute encoding the ByteString values of String resources, only base64 is supported
+	ENCODING = "encoding"
+	// URLRawQuery attribute holding the query parameters of a command, added by the SDK
+	URLRawQuery = "urlRawQuery"
 )
 
+// Base64Encoding is the value of the encoding attribute reading and writing ByteString values as base64 strings
+const Base64Encoding = "base64"
+
 const (
 	// Protocol is the supported device protocol
 	Protocol = "opcua"
 	// Endpoint is a constant string
 	Endpoint = "Endpoint"
+	// MaxAge protocol property, the maximum age of values read from the server cache
+	MaxAge = "MaxAge"
+	// RequestTimeout protocol property, the timeout of requests to the server
+	RequestTimeout = "RequestTimeout"
+	// SessionTimeout protocol property, the timeout of the session with the server
+	SessionTimeout = "SessionTimeout"
+)
+
+const (
+	// TimestampSource takes the reading origin from the OPC UA SourceTimestamp
+	TimestampSource = "Source"
+	// TimestampServer takes the reading origin from the OPC UA ServerTimestamp
+	TimestampServer = "Server"
+	// TimestampLocal takes the reading origin from the local clock
+	TimestampLocal = "Local"
+)
+
+const (
+	// QualityPublish publishes values regardless of their status code
+	QualityPublish = "Publish"
+	// QualityDrop drops values whose status code is not Good
+	QualityDrop = "Drop"
+	// QualityReplace replaces values whose status code is not Good with the last Good value
+	QualityReplace = "Replace"
+)
+
+const (
+	// OverflowBlock blocks the subscription until the SDK accepts queued async values
+	OverflowBlock = "Block"
+	// OverflowDropOldest drops the oldest queued async values to queue new ones
+	OverflowDropOldest = "DropOldest"
+	// OverflowDropNewest drops new async values while the queue is full
+	OverflowDropNewest = "DropNewest"
+)
+
+const (
+	// GroupingNone publishes every value received by the subscription as an event of its own
+	GroupingNone = "None"
+	// GroupingDevice publishes the values of a device received in one notification as one event
+	GroupingDevice = "Device"
+	// GroupingCommand publishes the values of a device received in one notification as one event
+	// per device command holding their resources
+	GroupingCommand = "Command"
+)
+
+const (
+	// StatusCodeTag is the reading tag holding the OPC UA status code
+	StatusCodeTag = "statusCode"
+	// StatusNameTag is the reading tag holding the symbolic name of the OPC UA status code
+	StatusNameTag = "statusName"
+	// LocaleTag is the reading tag holding the locale of an OPC UA LocalizedText
+	LocaleTag = "locale"
+	// UnitsTag is the reading tag holding the OPC UA EngineeringUnits of the node
+	UnitsTag = "units"
 )
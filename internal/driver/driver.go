@@ -12,6 +12,8 @@ package driver
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,10 +35,38 @@ type Driver struct {
 	AsyncCh       chan<- *sdkModel.AsyncValues
 	sdkService    interfaces.DeviceServiceSDK
 	serviceConfig *ServiceConfig
-	resourceMap   map[uint32]string
 	mu            sync.Mutex
 	ctxCancel     context.CancelFunc
-	clientMap     map[string]*opcua.Client
+	clientMap     map[clientKey]*opcua.Client
+	// client last used per device, released when the device is updated or removed
+	deviceClients map[string]clientKey
+	// cancelled when the service stops, ending pending requests
+	serviceCtx    context.Context
+	serviceCancel context.CancelFunc
+	serviceCtxMu  sync.Mutex
+	// last Good value per device resource, used by the Replace quality policy
+	lastGoodValues map[string]sdkModel.CommandValue
+	lastGoodMu     sync.Mutex
+	// data type definitions of structured node values
+	dataTypes dataTypeCache
+	// engineering units per node, used when the profile does not define units
+	engineeringUnits map[string]string
+	unitsMu          sync.Mutex
+	// input and output arguments of the methods called per device
+	methods   map[string]*methodSignature
+	methodsMu sync.Mutex
+	// node ids resolved from browse paths
+	nodeIDs nodeIDCache
+	// node ids registered for polled resources
+	registeredNodes nodeRegistrations
+	// queue of async values sent to the SDK
+	publisher *asyncPublisher
+	// device resources of the items monitored by subscriptions
+	monitoredItems monitoredItemRegistry
+	// watches of the connection state of the clients
+	watches clientWatches
+	// last values published per monitored resource, used by backfills
+	lastPublished publishedValues
 }
 
 // NewProtocolDriver returns a new protocol driver object
@@ -54,8 +84,7 @@ func (d *Driver) Initialize(sdk interfaces.DeviceServiceSDK) error {
 	d.AsyncCh = sdk.AsyncValuesChannel()
 	d.serviceConfig = &ServiceConfig{}
 	d.mu.Lock()
-	d.resourceMap = make(map[uint32]string)
-	d.clientMap = make(map[string]*opcua.Client)
+	d.clientMap = make(map[clientKey]*opcua.Client)
 	d.mu.Unlock()
 
 	if err := sdk.LoadCustomConfig(d.serviceConfig, CustomConfigSectionName); err != nil {
@@ -68,6 +97,12 @@ func (d *Driver) Initialize(sdk interfaces.DeviceServiceSDK) error {
 		return errors.NewCommonEdgeXWrapper(err)
 	}
 
+	d.publisher = newAsyncPublisher(d.serviceConfig.OPCUAServer.AsyncBufferSize, d.serviceConfig.OPCUAServer.AsyncOverflowPolicy, d.Logger)
+	go d.publisher.run(d.serviceContext(), d.AsyncCh)
+	if manager := sdk.MetricsManager(); manager != nil {
+		d.publisher.registerMetrics(manager)
+	}
+
 	if err := sdk.ListenForCustomConfigChanges(&d.serviceConfig.OPCUAServer.Writable, WritableInfoSectionName, d.updateWritableConfig); err != nil {
 		return errors.NewCommonEdgeX(errors.Kind(err), fmt.Sprintf("unable to listen for changes for '%s' custom configuration", WritableInfoSectionName), err)
 	}
@@ -121,6 +156,10 @@ func (d *Driver) AddDevice(deviceName string, protocols map[string]models.Protoc
 // UpdateDevice is a callback function that is invoked
 // when a Device associated with this Device Service is updated
 func (d *Driver) UpdateDevice(deviceName string, protocols map[string]models.ProtocolProperties, adminState models.AdminState) error {
+	d.forgetMethods(deviceName)
+	d.forgetRegisteredNodes(deviceName)
+	// the client is built again with the updated protocol properties
+	d.releaseDeviceClient(deviceName)
 	d.Logger.Debugf("Device %s is updated", deviceName)
 	return nil
 }
@@ -128,6 +167,9 @@ func (d *Driver) UpdateDevice(deviceName string, protocols map[string]models.Pro
 // RemoveDevice is a callback function that is invoked
 // when a Device associated with this Device Service is removed
 func (d *Driver) RemoveDevice(deviceName string, protocols map[string]models.ProtocolProperties) error {
+	d.forgetMethods(deviceName)
+	d.forgetRegisteredNodes(deviceName)
+	d.releaseDeviceClient(deviceName)
 	d.Logger.Debugf("Device %s is removed", deviceName)
 	return nil
 }
@@ -141,15 +183,32 @@ func (d *Driver) Start() error {
 // for closing any in-use channels, including the channel used to send async
 // readings (if supported).
 func (d *Driver) Stop(force bool) error {
+	var clients []*opcua.Client
 	d.mu.Lock()
-	d.resourceMap = nil
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	for _, cli := range d.clientMap {
-		cli.Close(ctx)
+	for key, cli := range d.clientMap {
+		clients = append(clients, cli)
+		delete(d.clientMap, key)
 	}
+	d.deviceClients = nil
 	d.mu.Unlock()
+	// released without holding the lock, as each release waits for the server
+	for _, cli := range clients {
+		d.releaseClient(cli)
+	}
 	d.cleanup()
+	d.serviceCtxMu.Lock()
+	if d.serviceCancel != nil {
+		d.serviceCancel()
+	}
+	d.serviceCtxMu.Unlock()
+	if d.publisher != nil {
+		if oldest, newest := d.publisher.droppedReadings(); oldest+newest > 0 {
+			d.Logger.Warnf("%d oldest and %d newest async readings were dropped", oldest, newest)
+		}
+		if manager := d.sdkService.MetricsManager(); manager != nil {
+			d.publisher.unregisterMetrics(manager)
+		}
+	}
 	return nil
 }
 
@@ -162,9 +221,54 @@ func (d *Driver) ValidateDevice(device models.Device) error {
 	if err != nil {
 		return fmt.Errorf("invalid protocol properties, %v", err)
 	}
+	if _, err := fetchDeviceSettings(device.Protocols); err != nil {
+		return fmt.Errorf("invalid protocol properties, %v", err)
+	}
+	if err := d.validateResources(device); err != nil {
+		return fmt.Errorf("invalid device resource, %v", err)
+	}
 	return nil
 }
 
+// serviceContext returns the context of requests to the servers, which is cancelled
+// when the service stops
+func (d *Driver) serviceContext() context.Context {
+	d.serviceCtxMu.Lock()
+	defer d.serviceCtxMu.Unlock()
+	if d.serviceCtx == nil {
+		d.serviceCtx, d.serviceCancel = context.WithCancel(context.Background())
+	}
+	return d.serviceCtx
+}
+
+// requestContext returns the context of a request to the server of a device, bounded
+// by the RequestTimeout of the device and cancelled when the service stops
+func (d *Driver) requestContext(settings deviceSettings) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(d.serviceContext(), settings.RequestTimeout)
+}
+
+// maxAge returns the maximum age of values read for a resource in milliseconds, given by
+// the maxAge attribute or the MaxAge protocol property of the device
+func (d *Driver) maxAge(settings deviceSettings, attrs map[string]interface{}) (float64, error) {
+	value, ok := attrs[MAXAGE]
+	if !ok {
+		return settings.MaxAge, nil
+	}
+	maxAge, err := parseMilliseconds(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %v: %v", MAXAGE, value, err)
+	}
+	return float64(maxAge) / float64(time.Millisecond), nil
+}
+
+// timestampSource returns the configured source of the reading origin
+func (d *Driver) timestampSource() string {
+	if d.serviceConfig == nil {
+		return ""
+	}
+	return d.serviceConfig.OPCUAServer.TimestampSource
+}
+
 func getNodeID(attrs map[string]interface{}, id string) (string, error) {
 	identifier, ok := attrs[id]
 	if !ok {
@@ -174,17 +278,127 @@ func getNodeID(attrs map[string]interface{}, id string) (string, error) {
 	return identifier.(string), nil
 }
 
-func (d *Driver) buildClient(ctx context.Context, endpoint string) (*opcua.Client, error) {
+// getAttributeID returns the node attribute named by the attributeId attribute,
+// or the Value attribute when it is not defined
+func getAttributeID(attrs map[string]interface{}) (ua.AttributeID, error) {
+	attribute, ok := attrs[ATTRIBUTE]
+	if !ok {
+		return ua.AttributeIDValue, nil
+	}
+
+	name := fmt.Sprintf("%v", attribute)
+	for id := ua.AttributeIDNodeID; id <= ua.AttributeIDAccessLevelEx; id++ {
+		if strings.EqualFold(name, strings.TrimPrefix(id.String(), "AttributeID")) {
+			return id, nil
+		}
+	}
+	return ua.AttributeIDInvalid, fmt.Errorf("attribute %s has unknown value %s", ATTRIBUTE, name)
+}
+
+// getInputMap returns the static input arguments of a method defined by the inputMap attribute
+func getInputMap(attrs map[string]interface{}) ([]interface{}, error) {
+	inputMap, ok := attrs[INPUTMAP]
+	if !ok {
+		return nil, nil
+	}
+
+	elements, ok := inputMap.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("attribute %s must be a list, got %T", INPUTMAP, inputMap)
+	}
+	return elements, nil
+}
+
+// getIndexRange returns the validated indexRange attribute, or an empty string when it is not defined.
+// A range selects one element ("5") or a sub-range ("2:7") per dimension, separated by commas.
+func getIndexRange(attrs map[string]interface{}) (string, error) {
+	attribute, ok := attrs[INDEXRANGE]
+	if !ok {
+		return "", nil
+	}
+
+	indexRange := strings.ReplaceAll(fmt.Sprintf("%v", attribute), " ", "")
+	for _, dimension := range strings.Split(indexRange, ",") {
+		bounds := strings.Split(dimension, ":")
+		if len(bounds) > 2 {
+			return "", fmt.Errorf("attribute %s has invalid value %s", INDEXRANGE, indexRange)
+		}
+		var lower uint64
+		for i, bound := range bounds {
+			index, err := strconv.ParseUint(bound, 10, 32)
+			if err != nil {
+				return "", fmt.Errorf("attribute %s has invalid value %s", INDEXRANGE, indexRange)
+			}
+			if i == 1 && index <= lower {
+				return "", fmt.Errorf("attribute %s has invalid value %s, the upper bound must be greater than the lower bound", INDEXRANGE, indexRange)
+			}
+			lower = index
+		}
+	}
+
+	return indexRange, nil
+}
+
+// clientKey identifies the client shared by the devices of an endpoint with the same client settings
+type clientKey struct {
+	endpoint       string
+	requestTimeout time.Duration
+	sessionTimeout time.Duration
+}
+
+// buildClient returns the connected client of a device endpoint, shared by the devices with
+// the same RequestTimeout and SessionTimeout
+func (d *Driver) buildClient(deviceName string, endpoint string, settings deviceSettings) (*opcua.Client, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	if client, ok := d.clientMap[endpoint]; ok {
+	key := clientKey{endpoint: endpoint, requestTimeout: settings.RequestTimeout, sessionTimeout: settings.SessionTimeout}
+	if d.deviceClients == nil {
+		d.deviceClients = make(map[string]clientKey)
+	}
+	d.deviceClients[deviceName] = key
+	if client, ok := d.clientMap[key]; ok {
 		return client, nil
 	}
 
-	client, _ := opcua.NewClient(endpoint, opcua.SecurityMode(ua.MessageSecurityModeNone))
+	opts := []opcua.Option{
+		opcua.SecurityMode(ua.MessageSecurityModeNone),
+		opcua.RequestTimeout(settings.RequestTimeout),
+	}
+	if settings.SessionTimeout > 0 {
+		opts = append(opts, opcua.SessionTimeout(settings.SessionTimeout))
+	}
+	client, err := d.newWatchedClient(endpoint, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(d.serviceContext(), settings.RequestTimeout)
+	defer cancel()
 	if err := client.Connect(ctx); err != nil {
+		d.releaseClient(client)
 		return nil, err
 	}
-	d.clientMap[endpoint] = client
+	d.clientMap[key] = client
 	return client, nil
 }
+
+// releaseDeviceClient releases the client last used by a device, unless other devices use it
+// as well, so that the next command of the device connects with its current protocol properties
+func (d *Driver) releaseDeviceClient(deviceName string) {
+	d.mu.Lock()
+	key, ok := d.deviceClients[deviceName]
+	delete(d.deviceClients, deviceName)
+	for _, other := range d.deviceClients {
+		if other == key {
+			ok = false
+			break
+		}
+	}
+	client, found := d.clientMap[key]
+	if !ok || !found {
+		d.mu.Unlock()
+		return
+	}
+	delete(d.clientMap, key)
+	d.mu.Unlock()
+	d.releaseClient(client)
+}
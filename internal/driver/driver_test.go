@@ -9,10 +9,16 @@ package driver
 
 import (
 	"context"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/clients/logger"
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDriver_updateWritableConfig(t *testing.T) {
@@ -196,3 +202,171 @@ func Test_getNodeID(t *testing.T) {
 		})
 	}
 }
+
+func Test_getAttributeID(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   map[string]interface{}
+		want    ua.AttributeID
+		wantErr bool
+	}{
+		{
+			name:  "OK - value attribute by default",
+			attrs: map[string]interface{}{NODE: "ns=2;s=edgex/int32/var0"},
+			want:  ua.AttributeIDValue,
+		},
+		{
+			name:  "OK - display name attribute",
+			attrs: map[string]interface{}{NODE: "ns=2;s=edgex/int32/var0", ATTRIBUTE: "DisplayName"},
+			want:  ua.AttributeIDDisplayName,
+		},
+		{
+			name:  "OK - attribute name is case insensitive",
+			attrs: map[string]interface{}{NODE: "ns=2;s=edgex/int32/var0", ATTRIBUTE: "minimumSamplingInterval"},
+			want:  ua.AttributeIDMinimumSamplingInterval,
+		},
+		{
+			name:    "NOK - unknown attribute",
+			attrs:   map[string]interface{}{NODE: "ns=2;s=edgex/int32/var0", ATTRIBUTE: "Color"},
+			want:    ua.AttributeIDInvalid,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getAttributeID(tt.attrs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getAttributeID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("getAttributeID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getIndexRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "OK - no index range", attrs: map[string]interface{}{}, want: ""},
+		{name: "OK - single element", attrs: map[string]interface{}{INDEXRANGE: "5"}, want: "5"},
+		{name: "OK - numeric single element", attrs: map[string]interface{}{INDEXRANGE: 5}, want: "5"},
+		{name: "OK - sub range", attrs: map[string]interface{}{INDEXRANGE: "2:7"}, want: "2:7"},
+		{name: "OK - multi-dimensional range", attrs: map[string]interface{}{INDEXRANGE: "1:2, 0"}, want: "1:2,0"},
+		{name: "NOK - negative index", attrs: map[string]interface{}{INDEXRANGE: "-1"}, wantErr: true},
+		{name: "NOK - upper bound not greater than lower bound", attrs: map[string]interface{}{INDEXRANGE: "7:2"}, wantErr: true},
+		{name: "NOK - too many bounds", attrs: map[string]interface{}{INDEXRANGE: "1:2:3"}, wantErr: true},
+		{name: "NOK - not a number", attrs: map[string]interface{}{INDEXRANGE: "first"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getIndexRange(tt.attrs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getIndexRange() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("getIndexRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getInputMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   map[string]interface{}
+		want    []interface{}
+		wantErr bool
+	}{
+		{name: "OK - no inputMap", attrs: map[string]interface{}{}, want: nil},
+		{name: "OK - mixed values", attrs: map[string]interface{}{INPUTMAP: []interface{}{"on", 12.5, 3}}, want: []interface{}{"on", 12.5, 3}},
+		{name: "NOK - not a list", attrs: map[string]interface{}{INPUTMAP: "on"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getInputMap(tt.attrs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getInputMap() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getInputMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDriver_maxAge(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   map[string]interface{}
+		want    float64
+		wantErr bool
+	}{
+		{name: "OK - device default", attrs: map[string]interface{}{}, want: 2000},
+		{name: "OK - resource milliseconds", attrs: map[string]interface{}{MAXAGE: 100}, want: 100},
+		{name: "OK - resource duration", attrs: map[string]interface{}{MAXAGE: "1m"}, want: 60000},
+		{name: "OK - resource reads from the device", attrs: map[string]interface{}{MAXAGE: 0}, want: 0},
+		{name: "NOK - invalid value", attrs: map[string]interface{}{MAXAGE: "old"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Driver{}
+			got, err := d.maxAge(deviceSettings{MaxAge: 2000}, tt.attrs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("maxAge() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("maxAge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDriver_Stop_cancelsRequests(t *testing.T) {
+	d := &Driver{Logger: &logger.MockLogger{}}
+	ctx, cancel := d.requestContext(deviceSettings{RequestTimeout: time.Minute})
+	defer cancel()
+
+	if err := d.Stop(false); err != nil {
+		t.Fatalf("Driver.Stop() error = %v", err)
+	}
+	select {
+	case <-ctx.Done():
+	default:
+		t.Error("request context not cancelled when the service stops")
+	}
+}
+
+func TestDriver_releaseDeviceClient(t *testing.T) {
+	d := &Driver{Logger: &logger.MockLogger{}, clientMap: map[clientKey]*opcua.Client{}}
+	shared := clientKey{endpoint: "opc.tcp://machine:4840", requestTimeout: 10 * time.Second}
+	own := clientKey{endpoint: "opc.tcp://machine:4840", requestTimeout: time.Second, sessionTimeout: time.Minute}
+	for _, key := range []clientKey{shared, own} {
+		client, err := d.newWatchedClient(key.endpoint, nil)
+		require.NoError(t, err)
+		d.clientMap[key] = client
+	}
+	d.deviceClients = map[string]clientKey{"Press": shared, "Oven": shared, "Lathe": own}
+
+	d.releaseDeviceClient("Press")
+	assert.Contains(t, d.clientMap, shared, "a client used by other devices is kept")
+	assert.NotContains(t, d.deviceClients, "Press")
+
+	d.releaseDeviceClient("Lathe")
+	assert.NotContains(t, d.clientMap, own, "the client of the device only is released")
+	assert.Contains(t, d.clientMap, shared)
+
+	d.releaseDeviceClient("Oven")
+	assert.Empty(t, d.clientMap, "the client is released with its last device")
+	assert.Empty(t, d.watches.done)
+
+	d.releaseDeviceClient("Unknown")
+}
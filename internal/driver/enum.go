@@ -0,0 +1,132 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+)
+
+const (
+	enumStringsProperty = "EnumStrings"
+	enumValuesProperty  = "EnumValues"
+)
+
+// enumDefinitionStrings returns the display text of the fields of an EnumDefinition
+func enumDefinitionStrings(definition *ua.EnumDefinition) map[int64]string {
+	enumStrings := make(map[int64]string, len(definition.Fields))
+	for _, field := range definition.Fields {
+		if field.DisplayName != nil && field.DisplayName.Text != "" {
+			enumStrings[field.Value] = field.DisplayName.Text
+		} else {
+			enumStrings[field.Value] = field.Name
+		}
+	}
+	return enumStrings
+}
+
+// enumPropertyStrings reads the display text of the values of an enumeration
+// from the EnumStrings or EnumValues property of the data type. A data type without
+// either property, or with a property holding no value, has no display texts.
+func enumPropertyStrings(ctx context.Context, client *opcua.Client, dataTypeID *ua.NodeID) (map[int64]string, error) {
+	enumStrings := make(map[int64]string)
+
+	value, err := enumProperty(ctx, client, dataTypeID, enumStringsProperty)
+	if err != nil {
+		return nil, err
+	}
+	if value != nil {
+		texts, _ := value.Value().([]*ua.LocalizedText)
+		for i, text := range texts {
+			if text != nil {
+				enumStrings[int64(i)] = text.Text
+			}
+		}
+		return enumStrings, nil
+	}
+
+	value, err = enumProperty(ctx, client, dataTypeID, enumValuesProperty)
+	if err != nil || value == nil {
+		return enumStrings, err
+	}
+	eos, _ := value.Value().([]*ua.ExtensionObject)
+	for _, eo := range eos {
+		if eo == nil {
+			continue
+		}
+		if enumValue, ok := eo.Value.(*ua.EnumValueType); ok && enumValue.DisplayName != nil {
+			enumStrings[enumValue.Value] = enumValue.DisplayName.Text
+		}
+	}
+	return enumStrings, nil
+}
+
+// enumProperty reads a property of an enumeration data type. It returns a nil value
+// when the data type has no such property or the property holds no value.
+func enumProperty(ctx context.Context, client *opcua.Client, dataTypeID *ua.NodeID, property string) (*ua.Variant, error) {
+	propertyID, err := client.Node(dataTypeID).TranslateBrowsePathsToNodeIDs(ctx, []*ua.QualifiedName{{Name: property}})
+	if err != nil {
+		if err == ua.StatusBadNoMatch {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to find %s of data type %s: %v", property, dataTypeID, err)
+	}
+
+	value, err := client.Node(propertyID).Value(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s of data type %s: %v", property, dataTypeID, err)
+	}
+	return value, nil
+}
+
+// enumText replaces the integer values of an enumeration reading with their display text.
+// Values without a display text are kept as integer.
+func enumText(def *dataTypeDefinition, reading interface{}) interface{} {
+	if def == nil || def.enumStrings == nil {
+		return reading
+	}
+
+	switch v := reading.(type) {
+	case int32:
+		if text, ok := def.enumStrings[int64(v)]; ok {
+			return text
+		}
+	case []int32:
+		texts := make([]interface{}, len(v))
+		for i, value := range v {
+			texts[i] = enumText(def, value)
+		}
+		return texts
+	}
+	return reading
+}
+
+// enumWriteValue converts the display text of an enumeration value back into its integer value.
+// The integer value itself is accepted as well.
+func enumWriteValue(def *dataTypeDefinition, value interface{}) (interface{}, error) {
+	if def == nil || def.enumStrings == nil {
+		return value, nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	for enumValue, enumString := range def.enumStrings {
+		if enumString == text {
+			return int32(enumValue), nil // #nosec G115
+		}
+	}
+	if enumValue, err := strconv.ParseInt(text, 10, 32); err == nil {
+		return int32(enumValue), nil
+	}
+	return nil, fmt.Errorf("%q is not a value of enumeration %s", text, def.dataTypeID)
+}
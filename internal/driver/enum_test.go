@@ -0,0 +1,108 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"testing"
+
+	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func machineStateDefinition() *dataTypeDefinition {
+	return &dataTypeDefinition{
+		dataTypeID: ua.NewNumericNodeID(2, 3010),
+		builtin:    ua.TypeIDInt32,
+		enumStrings: enumDefinitionStrings(&ua.EnumDefinition{Fields: []*ua.EnumField{
+			{Value: 0, Name: "Stopped", DisplayName: ua.NewLocalizedText("Stopped")},
+			{Value: 1, Name: "Running"},
+			{Value: 5, Name: "Fault", DisplayName: ua.NewLocalizedText("In Fault")},
+		}}),
+	}
+}
+
+func Test_enumText(t *testing.T) {
+	def := machineStateDefinition()
+
+	tests := []struct {
+		name    string
+		def     *dataTypeDefinition
+		reading interface{}
+		want    interface{}
+	}{
+		{"display text", def, int32(5), "In Fault"},
+		{"name without display text", def, int32(1), "Running"},
+		{"unknown value kept", def, int32(3), int32(3)},
+		{"array", def, []int32{0, 1}, []interface{}{"Stopped", "Running"}},
+		{"no enumeration", builtinDefinition(ua.TypeIDInt32), int32(5), int32(5)},
+		{"no definition", nil, int32(5), int32(5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, enumText(tt.def, tt.reading))
+		})
+	}
+}
+
+func Test_enumWriteValue(t *testing.T) {
+	def := machineStateDefinition()
+
+	tests := []struct {
+		name    string
+		def     *dataTypeDefinition
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "OK - display text", def: def, value: "In Fault", want: int32(5)},
+		{name: "OK - integer text", def: def, value: "3", want: int32(3)},
+		{name: "OK - integer value", def: def, value: int32(1), want: int32(1)},
+		{name: "OK - no enumeration", def: builtinDefinition(ua.TypeIDString), value: "Running", want: "Running"},
+		{name: "NOK - unknown text", def: def, value: "Paused", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := enumWriteValue(tt.def, tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDriver_newDataValueResult_enum(t *testing.T) {
+	d := &Driver{}
+	d.dataTypes.storeNode("Test/ns=2;s=State", nil, machineStateDefinition())
+	dataValue := &ua.DataValue{Value: ua.MustVariant(int32(5)), Status: ua.StatusOK}
+
+	tests := []struct {
+		name      string
+		valueType string
+		want      interface{}
+	}{
+		{"String publishes the display text", common.ValueTypeString, "In Fault"},
+		{"Int32 publishes the integer", common.ValueTypeInt32, int32(5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := sdkModel.CommandRequest{
+				DeviceResourceName: "State",
+				Attributes:         map[string]interface{}{NODE: "ns=2;s=State"},
+				Type:               tt.valueType,
+			}
+			got, err := d.newDataValueResult("Test", req, dataValue)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Value)
+		})
+	}
+}
@@ -0,0 +1,447 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/id"
+	"github.com/gopcua/opcua/ua"
+	"github.com/spf13/cast"
+)
+
+const (
+	// HistoryRaw reads the raw values stored by the server for a node
+	HistoryRaw = "Raw"
+	// HistoryProcessed reads values aggregated by the server over processing intervals
+	HistoryProcessed = "Processed"
+	// HistoryEvents reads the events stored by the server for a notifier node
+	HistoryEvents = "Events"
+)
+
+const (
+	// query parameters of history read commands
+	historyStartParam     = "start"
+	historyEndParam       = "end"
+	historyMaxValuesParam = "maxValues"
+	historyModifiedParam  = "modified"
+	historyAggregateParam = "aggregate"
+	historyIntervalParam  = "interval"
+
+	// defaultHistoryWindow is the time window read when the command does not start it
+	defaultHistoryWindow = time.Hour
+)
+
+// historyWindow is the time window of a history read command
+type historyWindow struct {
+	start     time.Time
+	end       time.Time
+	maxValues uint32
+	modified  bool
+}
+
+// historyKind returns the kind of history read by a resource
+func historyKind(attrs map[string]interface{}) (string, error) {
+	kind := fmt.Sprintf("%v", attrs[HISTORY])
+	switch kind {
+	case HistoryRaw, HistoryProcessed, HistoryEvents:
+		return kind, nil
+	}
+	return "", fmt.Errorf("invalid %s attribute %s, expected %s, %s or %s", HISTORY, kind, HistoryRaw, HistoryProcessed, HistoryEvents)
+}
+
+// checkHistoryAttributes checks the history attributes of a resource
+func checkHistoryAttributes(attrs map[string]interface{}) error {
+	kind, err := historyKind(attrs)
+	if err != nil {
+		return err
+	}
+	if kind == HistoryEvents {
+		return checkEventAttributes(attrs)
+	}
+	if kind != HistoryProcessed {
+		return nil
+	}
+	if name, ok := attrs[AGGREGATE]; ok {
+		if _, err := aggregateNodeID(fmt.Sprintf("%v", name)); err != nil {
+			return err
+		}
+	}
+	if interval, ok := attrs[PROCESSINGINTERVAL]; ok {
+		if _, err := parseMilliseconds(interval); err != nil {
+			return fmt.Errorf("invalid %s attribute: %v", PROCESSINGINTERVAL, err)
+		}
+	}
+	return nil
+}
+
+// aggregateFunctions are the aggregates defined by OPC UA Part 13, by name
+var aggregateFunctions = map[string]uint32{
+	"Interpolative":               id.AggregateFunction_Interpolative,
+	"Average":                     id.AggregateFunction_Average,
+	"TimeAverage":                 id.AggregateFunction_TimeAverage,
+	"TimeAverage2":                id.AggregateFunction_TimeAverage2,
+	"Total":                       id.AggregateFunction_Total,
+	"Total2":                      id.AggregateFunction_Total2,
+	"Minimum":                     id.AggregateFunction_Minimum,
+	"Maximum":                     id.AggregateFunction_Maximum,
+	"MinimumActualTime":           id.AggregateFunction_MinimumActualTime,
+	"MaximumActualTime":           id.AggregateFunction_MaximumActualTime,
+	"Range":                       id.AggregateFunction_Range,
+	"Minimum2":                    id.AggregateFunction_Minimum2,
+	"Maximum2":                    id.AggregateFunction_Maximum2,
+	"MinimumActualTime2":          id.AggregateFunction_MinimumActualTime2,
+	"MaximumActualTime2":          id.AggregateFunction_MaximumActualTime2,
+	"Range2":                      id.AggregateFunction_Range2,
+	"Count":                       id.AggregateFunction_Count,
+	"AnnotationCount":             id.AggregateFunction_AnnotationCount,
+	"DurationInStateZero":         id.AggregateFunction_DurationInStateZero,
+	"DurationInStateNonZero":      id.AggregateFunction_DurationInStateNonZero,
+	"NumberOfTransitions":         id.AggregateFunction_NumberOfTransitions,
+	"Start":                       id.AggregateFunction_Start,
+	"End":                         id.AggregateFunction_End,
+	"Delta":                       id.AggregateFunction_Delta,
+	"StartBound":                  id.AggregateFunction_StartBound,
+	"EndBound":                    id.AggregateFunction_EndBound,
+	"DeltaBounds":                 id.AggregateFunction_DeltaBounds,
+	"DurationGood":                id.AggregateFunction_DurationGood,
+	"DurationBad":                 id.AggregateFunction_DurationBad,
+	"PercentGood":                 id.AggregateFunction_PercentGood,
+	"PercentBad":                  id.AggregateFunction_PercentBad,
+	"WorstQuality":                id.AggregateFunction_WorstQuality,
+	"WorstQuality2":               id.AggregateFunction_WorstQuality2,
+	"StandardDeviationSample":     id.AggregateFunction_StandardDeviationSample,
+	"StandardDeviationPopulation": id.AggregateFunction_StandardDeviationPopulation,
+	"VarianceSample":              id.AggregateFunction_VarianceSample,
+	"VariancePopulation":          id.AggregateFunction_VariancePopulation,
+}
+
+// aggregateNodeID returns the node of an aggregate function given by name
+func aggregateNodeID(name string) (*ua.NodeID, error) {
+	function, ok := aggregateFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown aggregate %s", name)
+	}
+	return ua.NewNumericNodeID(0, function), nil
+}
+
+// processedDetails returns the details of a processed history read. The aggregate and the
+// processing interval of the resource attributes may be overridden by query parameters.
+// Without a processing interval, the aggregate is computed over the whole window. The server
+// returns one value per interval, so the maxValues and modified parameters are rejected.
+func processedDetails(attrs map[string]interface{}, params map[string]interface{}, window historyWindow) (*ua.ReadProcessedDetails, error) {
+	for _, param := range []string{historyMaxValuesParam, historyModifiedParam} {
+		if _, ok := params[param]; ok {
+			return nil, fmt.Errorf("the %s parameter does not apply to %s history reads", param, HistoryProcessed)
+		}
+	}
+	name, ok := params[historyAggregateParam]
+	if !ok {
+		if name, ok = attrs[AGGREGATE]; !ok {
+			return nil, fmt.Errorf("no aggregate given by the %s attribute or the %s parameter", AGGREGATE, historyAggregateParam)
+		}
+	}
+	aggregate, err := aggregateNodeID(fmt.Sprintf("%v", name))
+	if err != nil {
+		return nil, err
+	}
+
+	var interval time.Duration
+	if value, ok := params[historyIntervalParam]; ok {
+		if interval, err = parseMilliseconds(value); err != nil {
+			return nil, fmt.Errorf("invalid %s parameter: %v", historyIntervalParam, err)
+		}
+	} else if value, ok := attrs[PROCESSINGINTERVAL]; ok {
+		if interval, err = parseMilliseconds(value); err != nil {
+			return nil, fmt.Errorf("invalid %s attribute: %v", PROCESSINGINTERVAL, err)
+		}
+	}
+
+	return &ua.ReadProcessedDetails{
+		StartTime:              window.start,
+		EndTime:                window.end,
+		ProcessingInterval:     float64(interval) / float64(time.Millisecond),
+		AggregateType:          []*ua.NodeID{aggregate},
+		AggregateConfiguration: &ua.AggregateConfiguration{UseServerCapabilitiesDefaults: true},
+	}, nil
+}
+
+// parseHistoryWindow returns the time window given by the query parameters of a command.
+// The window ends now and starts one hour earlier unless the parameters say otherwise.
+func parseHistoryWindow(params map[string]interface{}, now time.Time) (historyWindow, error) {
+	window := historyWindow{end: now}
+
+	if value, ok := params[historyEndParam]; ok {
+		end, err := parseHistoryTime(fmt.Sprintf("%v", value), now)
+		if err != nil {
+			return historyWindow{}, fmt.Errorf("invalid %s parameter: %v", historyEndParam, err)
+		}
+		window.end = end
+	}
+	window.start = window.end.Add(-defaultHistoryWindow)
+	if value, ok := params[historyStartParam]; ok {
+		start, err := parseHistoryTime(fmt.Sprintf("%v", value), now)
+		if err != nil {
+			return historyWindow{}, fmt.Errorf("invalid %s parameter: %v", historyStartParam, err)
+		}
+		window.start = start
+	}
+	if !window.start.Before(window.end) {
+		return historyWindow{}, fmt.Errorf("%s %s is not before %s %s", historyStartParam,
+			window.start.Format(time.RFC3339Nano), historyEndParam, window.end.Format(time.RFC3339Nano))
+	}
+
+	if value, ok := params[historyMaxValuesParam]; ok {
+		maxValues, err := cast.ToUint32E(value)
+		if err != nil {
+			return historyWindow{}, fmt.Errorf("invalid %s parameter: %v", historyMaxValuesParam, err)
+		}
+		window.maxValues = maxValues
+	}
+	if value, ok := params[historyModifiedParam]; ok {
+		modified, err := cast.ToBoolE(value)
+		if err != nil {
+			return historyWindow{}, fmt.Errorf("invalid %s parameter: %v", historyModifiedParam, err)
+		}
+		window.modified = modified
+	}
+	return window, nil
+}
+
+// parseHistoryTime parses an RFC 3339 time, or a duration relative to now such as -15m
+func parseHistoryTime(value string, now time.Time) (time.Time, error) {
+	if value == "now" {
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	offset, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s is neither an RFC 3339 time nor a duration", value)
+	}
+	return now.Add(offset), nil
+}
+
+func (d *Driver) makeHistoryRead(deviceName string, deviceClient *opcua.Client, settings deviceSettings, req sdkModel.CommandRequest) (*sdkModel.CommandValue, error) {
+	kind, err := historyKind(req.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
+	}
+
+	nodeID, err := d.resourceNodeID(deviceName, deviceClient, req.Attributes, NODE)
+	if err != nil {
+		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
+	}
+
+	indexRange, err := getIndexRange(req.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
+	}
+
+	params := queryParameters(req.Attributes)
+	window, err := parseHistoryWindow(params, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
+	}
+	if kind == HistoryEvents {
+		return d.makeEventHistoryRead(deviceName, deviceClient, settings, req, nodeID, params, window)
+	}
+	var details interface{}
+	if kind == HistoryProcessed {
+		details, err = processedDetails(req.Attributes, params, window)
+		if err != nil {
+			return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
+		}
+	} else {
+		details = &ua.ReadRawModifiedDetails{
+			IsReadModified:   window.modified,
+			StartTime:        window.start,
+			EndTime:          window.end,
+			NumValuesPerNode: window.maxValues,
+		}
+	}
+
+	ctx, cancel := d.requestContext(settings)
+	defer cancel()
+	node := &ua.HistoryReadValueID{NodeID: nodeID, IndexRange: indexRange, DataEncoding: &ua.QualifiedName{}}
+	values, err := historyValues(ctx, deviceClient, node, historyDetails(details), window.maxValues)
+	if err != nil {
+		if status, ok := err.(ua.StatusCode); ok {
+			d.checkNodeStatus(deviceClient, status)
+		}
+		return nil, fmt.Errorf("Driver.handleReadCommands: HistoryRead failed: %v", err)
+	}
+
+	result, err := d.historyResult(deviceName, req, values)
+	if err != nil {
+		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
+	}
+	return result, nil
+}
+
+// historyDetails wraps the details of a HistoryRead request in an extension object
+func historyDetails(details interface{}) *ua.ExtensionObject {
+	var typeID uint16
+	switch details.(type) {
+	case *ua.ReadRawModifiedDetails:
+		typeID = id.ReadRawModifiedDetails_Encoding_DefaultBinary
+	case *ua.ReadProcessedDetails:
+		typeID = id.ReadProcessedDetails_Encoding_DefaultBinary
+	case *ua.ReadEventDetails:
+		typeID = id.ReadEventDetails_Encoding_DefaultBinary
+	}
+	return &ua.ExtensionObject{
+		TypeID:       ua.NewFourByteExpandedNodeID(0, typeID),
+		EncodingMask: ua.ExtensionObjectBinary,
+		Value:        details,
+	}
+}
+
+// historyRead reads the history of a node, following the continuation points returned by
+// the server. Every page of history data is passed to collect, which returns the number of
+// entries collected so far. When limit entries have been collected, the remaining ones are
+// released.
+func historyRead(ctx context.Context, client *opcua.Client, node *ua.HistoryReadValueID, details *ua.ExtensionObject, limit uint32,
+	collect func(data *ua.ExtensionObject) (int, error)) error {
+	for {
+		result, err := sendHistoryRead(ctx, client, &ua.HistoryReadRequest{
+			HistoryReadDetails: details,
+			TimestampsToReturn: ua.TimestampsToReturnBoth,
+			NodesToRead:        []*ua.HistoryReadValueID{node},
+		})
+		if err != nil {
+			return err
+		}
+		if !isGood(result.StatusCode) {
+			return result.StatusCode
+		}
+
+		collected, err := collect(result.HistoryData)
+		if err != nil {
+			return err
+		}
+		if len(result.ContinuationPoint) == 0 {
+			return nil
+		}
+
+		node.ContinuationPoint = result.ContinuationPoint
+		if limit > 0 && collected >= int(limit) {
+			// the entries left on the server are not needed
+			_, _ = sendHistoryRead(ctx, client, &ua.HistoryReadRequest{
+				HistoryReadDetails:        details,
+				TimestampsToReturn:        ua.TimestampsToReturnBoth,
+				ReleaseContinuationPoints: true,
+				NodesToRead:               []*ua.HistoryReadValueID{node},
+			})
+			return nil
+		}
+	}
+}
+
+// historyValues reads at most limit values from the history of a node, all values when limit is zero
+func historyValues(ctx context.Context, client *opcua.Client, node *ua.HistoryReadValueID, details *ua.ExtensionObject, limit uint32) ([]*ua.DataValue, error) {
+	var values []*ua.DataValue
+	err := historyRead(ctx, client, node, details, limit, func(data *ua.ExtensionObject) (int, error) {
+		page, err := historyDataValues(data)
+		values = append(values, page...)
+		return len(values), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(values) > int(limit) {
+		values = values[:limit]
+	}
+	return values, nil
+}
+
+func sendHistoryRead(ctx context.Context, client *opcua.Client, req *ua.HistoryReadRequest) (*ua.HistoryReadResult, error) {
+	var resp *ua.HistoryReadResponse
+	err := client.Send(ctx, req, func(v ua.Response) error {
+		r, ok := v.(*ua.HistoryReadResponse)
+		if !ok {
+			return fmt.Errorf("unexpected response %T", v)
+		}
+		resp = r
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != 1 {
+		return nil, fmt.Errorf("%d results returned for 1 node", len(resp.Results))
+	}
+	return resp.Results[0], nil
+}
+
+// historyDataValues returns the data values of a HistoryRead result
+func historyDataValues(data *ua.ExtensionObject) ([]*ua.DataValue, error) {
+	if data == nil || data.Value == nil {
+		return nil, nil
+	}
+	switch v := data.Value.(type) {
+	case *ua.HistoryData:
+		return v.DataValues, nil
+	case *ua.HistoryModifiedData:
+		return v.DataValues, nil
+	}
+	return nil, fmt.Errorf("unexpected history data %T", data.Value)
+}
+
+// historyResult creates the reading of the values read from the history of a node.
+// An Object reading lists every value along with its timestamps and status, an array
+// reading holds the Good values only.
+func (d *Driver) historyResult(deviceName string, req sdkModel.CommandRequest, values []*ua.DataValue) (*sdkModel.CommandValue, error) {
+	if req.Type == common.ValueTypeObject {
+		entries := make([]interface{}, len(values))
+		for i, value := range values {
+			var reading interface{}
+			if value.Value != nil {
+				var err error
+				reading, err = d.structureReading(deviceName, value.Value.Value())
+				if err != nil {
+					return nil, err
+				}
+			}
+			entries[i] = historyEntry(objectValue(reading), value)
+		}
+		return newResult(req, entries)
+	}
+
+	if _, ok := arrayElementTypes[req.Type]; !ok {
+		return nil, fmt.Errorf("history of resource %s requires the %s value type or an array value type", req.DeviceResourceName, common.ValueTypeObject)
+	}
+	readings := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		if isGood(value.Status) && value.Value != nil {
+			readings = append(readings, builtinValue(arrayElementTypes[req.Type], value.Value.Value()))
+		}
+	}
+	return newResult(req, readings)
+}
+
+// historyEntry returns the entry of an Object history reading for a value
+func historyEntry(reading interface{}, value *ua.DataValue) map[string]interface{} {
+	entry := map[string]interface{}{
+		"value":       reading,
+		StatusCodeTag: fmt.Sprintf("0x%08X", uint32(value.Status)),
+		StatusNameTag: statusName(value.Status),
+	}
+	if !value.SourceTimestamp.IsZero() {
+		entry["sourceTimestamp"] = value.SourceTimestamp.UTC().Format(time.RFC3339Nano)
+	}
+	if !value.ServerTimestamp.IsZero() {
+		entry["serverTimestamp"] = value.ServerTimestamp.UTC().Format(time.RFC3339Nano)
+	}
+	return entry
+}
@@ -0,0 +1,217 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"testing"
+	"time"
+
+	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseHistoryWindow(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		want    historyWindow
+		wantErr bool
+	}{
+		{
+			name: "OK - default window",
+			want: historyWindow{start: now.Add(-time.Hour), end: now},
+		},
+		{
+			name:   "OK - RFC 3339 times",
+			params: map[string]interface{}{"start": "2024-05-01T08:00:00Z", "end": "2024-05-01T09:30:00Z"},
+			want:   historyWindow{start: now.Add(-4 * time.Hour), end: now.Add(-150 * time.Minute)},
+		},
+		{
+			name:   "OK - relative start",
+			params: map[string]interface{}{"start": "-15m", "end": "now"},
+			want:   historyWindow{start: now.Add(-15 * time.Minute), end: now},
+		},
+		{
+			name:   "OK - window before a given end",
+			params: map[string]interface{}{"end": "-1h"},
+			want:   historyWindow{start: now.Add(-2 * time.Hour), end: now.Add(-time.Hour)},
+		},
+		{
+			name:   "OK - modified values limited",
+			params: map[string]interface{}{"maxValues": "100", "modified": "true"},
+			want:   historyWindow{start: now.Add(-time.Hour), end: now, maxValues: 100, modified: true},
+		},
+		{name: "NOK - invalid start", params: map[string]interface{}{"start": "yesterday"}, wantErr: true},
+		{name: "NOK - start after end", params: map[string]interface{}{"start": "-1h", "end": "-2h"}, wantErr: true},
+		{name: "NOK - invalid maxValues", params: map[string]interface{}{"maxValues": "-1"}, wantErr: true},
+		{name: "NOK - invalid modified", params: map[string]interface{}{"modified": "maybe"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHistoryWindow(tt.params, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.start.Equal(got.start), "start %v, want %v", got.start, tt.want.start)
+			assert.True(t, tt.want.end.Equal(got.end), "end %v, want %v", got.end, tt.want.end)
+			assert.Equal(t, tt.want.maxValues, got.maxValues)
+			assert.Equal(t, tt.want.modified, got.modified)
+		})
+	}
+}
+
+func Test_historyDataValues(t *testing.T) {
+	values := []*ua.DataValue{{Value: ua.MustVariant(1.5)}}
+	tests := []struct {
+		name    string
+		data    *ua.ExtensionObject
+		want    []*ua.DataValue
+		wantErr bool
+	}{
+		{name: "OK - no data", data: nil, want: nil},
+		{name: "OK - history data", data: &ua.ExtensionObject{Value: &ua.HistoryData{DataValues: values}}, want: values},
+		{name: "OK - modified data", data: &ua.ExtensionObject{Value: &ua.HistoryModifiedData{DataValues: values}}, want: values},
+		{name: "NOK - event data", data: &ua.ExtensionObject{Value: &ua.HistoryEvent{}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := historyDataValues(tt.data)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDriver_historyResult(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	values := []*ua.DataValue{
+		{Value: ua.MustVariant(1.5), Status: ua.StatusOK, SourceTimestamp: ts},
+		{Status: ua.StatusBadNoData, ServerTimestamp: ts.Add(time.Second)},
+		{Value: ua.MustVariant(2.5), Status: ua.StatusOK, SourceTimestamp: ts.Add(2 * time.Second)},
+	}
+	d := &Driver{}
+
+	tests := []struct {
+		name      string
+		valueType string
+		want      interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "OK - array of Good values",
+			valueType: common.ValueTypeFloat64Array,
+			want:      []float64{1.5, 2.5},
+		},
+		{
+			name:      "OK - object with timestamps",
+			valueType: common.ValueTypeObject,
+			want: []interface{}{
+				map[string]interface{}{"value": 1.5, StatusCodeTag: "0x00000000", StatusNameTag: "Good", "sourceTimestamp": "2024-05-01T12:00:00Z"},
+				map[string]interface{}{"value": nil, StatusCodeTag: "0x809B0000", StatusNameTag: "BadNoData", "serverTimestamp": "2024-05-01T12:00:01Z"},
+				map[string]interface{}{"value": 2.5, StatusCodeTag: "0x00000000", StatusNameTag: "Good", "sourceTimestamp": "2024-05-01T12:00:02Z"},
+			},
+		},
+		{name: "NOK - scalar value type", valueType: common.ValueTypeFloat64, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := sdkModel.CommandRequest{DeviceResourceName: "History", Type: tt.valueType}
+			got, err := d.historyResult("Test", req, values)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Value)
+		})
+	}
+}
+
+func Test_processedDetails(t *testing.T) {
+	window := historyWindow{start: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), end: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name          string
+		attrs         map[string]interface{}
+		params        map[string]interface{}
+		wantAggregate *ua.NodeID
+		wantInterval  float64
+		wantErr       bool
+	}{
+		{
+			name:          "OK - aggregate over the window",
+			attrs:         map[string]interface{}{AGGREGATE: "Average"},
+			wantAggregate: ua.NewNumericNodeID(0, 2342),
+		},
+		{
+			name:          "OK - hourly aggregate",
+			attrs:         map[string]interface{}{AGGREGATE: "Maximum", PROCESSINGINTERVAL: "1h"},
+			wantAggregate: ua.NewNumericNodeID(0, 2347),
+			wantInterval:  3600000,
+		},
+		{
+			name:          "OK - parameters override attributes",
+			attrs:         map[string]interface{}{AGGREGATE: "Maximum", PROCESSINGINTERVAL: "1h"},
+			params:        map[string]interface{}{"aggregate": "Count", "interval": "15000"},
+			wantAggregate: ua.NewNumericNodeID(0, 2352),
+			wantInterval:  15000,
+		},
+		{name: "NOK - no aggregate", attrs: map[string]interface{}{}, wantErr: true},
+		{name: "NOK - unknown aggregate", params: map[string]interface{}{"aggregate": "Median"}, wantErr: true},
+		{name: "NOK - invalid interval", attrs: map[string]interface{}{AGGREGATE: "Average"}, params: map[string]interface{}{"interval": "hourly"}, wantErr: true},
+		{name: "NOK - maxValues parameter", attrs: map[string]interface{}{AGGREGATE: "Average"}, params: map[string]interface{}{"maxValues": "10"}, wantErr: true},
+		{name: "NOK - modified parameter", attrs: map[string]interface{}{AGGREGATE: "Average"}, params: map[string]interface{}{"modified": "true"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := processedDetails(tt.attrs, tt.params, window)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []*ua.NodeID{tt.wantAggregate}, got.AggregateType)
+			assert.Equal(t, tt.wantInterval, got.ProcessingInterval)
+			assert.Equal(t, window.start, got.StartTime)
+			assert.Equal(t, window.end, got.EndTime)
+		})
+	}
+}
+
+func Test_checkHistoryAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   map[string]interface{}
+		wantErr bool
+	}{
+		{name: "OK - raw", attrs: map[string]interface{}{HISTORY: "Raw"}},
+		{name: "OK - processed", attrs: map[string]interface{}{HISTORY: "Processed", AGGREGATE: "TimeAverage", PROCESSINGINTERVAL: "1h"}},
+		{name: "OK - aggregate given per command", attrs: map[string]interface{}{HISTORY: "Processed"}},
+		{name: "NOK - unknown kind", attrs: map[string]interface{}{HISTORY: "Aggregated"}, wantErr: true},
+		{name: "NOK - unknown aggregate", attrs: map[string]interface{}{HISTORY: "Processed", AGGREGATE: "Mean"}, wantErr: true},
+		{name: "NOK - negative interval", attrs: map[string]interface{}{HISTORY: "Processed", PROCESSINGINTERVAL: "-1h"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkHistoryAttributes(tt.attrs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/id"
+	"github.com/gopcua/opcua/ua"
+	"github.com/spf13/cast"
+)
+
+// defaultEventFields are the fields of the BaseEventType returned for every event
+// when the resource does not select its own
+var defaultEventFields = []interface{}{"EventId", "EventType", "SourceName", "Time", "Message", "Severity"}
+
+// eventFields returns the browse paths of the event fields selected by a resource,
+// e.g. ["Time", "Message", "ActiveState/Id"], keyed by the path as given
+func eventFields(attrs map[string]interface{}) ([]string, [][]*ua.QualifiedName, error) {
+	fields := defaultEventFields
+	if attribute, ok := attrs[EVENTFIELDS]; ok {
+		if fields, ok = attribute.([]interface{}); !ok || len(fields) == 0 {
+			return nil, nil, fmt.Errorf("attribute %s must be a non-empty list, got %v", EVENTFIELDS, attribute)
+		}
+	}
+
+	names := make([]string, len(fields))
+	paths := make([][]*ua.QualifiedName, len(fields))
+	for i, field := range fields {
+		names[i] = fmt.Sprintf("%v", field)
+		for _, element := range strings.Split(names[i], "/") {
+			if element == "" {
+				return nil, nil, fmt.Errorf("invalid event field %s", names[i])
+			}
+			name, err := parseQualifiedName(element)
+			if err != nil {
+				return nil, nil, err
+			}
+			paths[i] = append(paths[i], name)
+		}
+	}
+	return names, paths, nil
+}
+
+// eventFilterSetting returns a setting of the event filter, given by a query parameter
+// or else by the resource attribute of the same name
+func eventFilterSetting(attrs, params map[string]interface{}, name string) (string, bool) {
+	if value, ok := params[name]; ok {
+		return fmt.Sprintf("%v", value), true
+	}
+	if value, ok := attrs[name]; ok {
+		return fmt.Sprintf("%v", value), true
+	}
+	return "", false
+}
+
+// eventFilter returns the filter selecting the event fields of a resource. Events may be
+// restricted to an event type and its subtypes with eventType, and to a minimum severity
+// with minSeverity.
+func eventFilter(attrs, params map[string]interface{}) (*ua.EventFilter, []string, error) {
+	names, paths, err := eventFields(attrs)
+	if err != nil {
+		return nil, nil, err
+	}
+	filter := &ua.EventFilter{SelectClauses: make([]*ua.SimpleAttributeOperand, len(paths))}
+	for i, path := range paths {
+		filter.SelectClauses[i] = eventFieldOperand(path)
+	}
+
+	var elements []*ua.ContentFilterElement
+	if value, ok := eventFilterSetting(attrs, params, EVENTTYPE); ok {
+		eventType, err := ua.ParseNodeID(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s %s: %v", EVENTTYPE, value, err)
+		}
+		elements = append(elements, &ua.ContentFilterElement{
+			FilterOperator: ua.FilterOperatorOfType,
+			FilterOperands: []*ua.ExtensionObject{ua.NewExtensionObject(&ua.LiteralOperand{Value: ua.MustVariant(eventType)})},
+		})
+	}
+	if value, ok := eventFilterSetting(attrs, params, MINSEVERITY); ok {
+		severity, err := cast.ToUint16E(value)
+		if err != nil || severity < 1 || severity > 1000 {
+			return nil, nil, fmt.Errorf("invalid %s %s, expected 1 to 1000", MINSEVERITY, value)
+		}
+		elements = append(elements, &ua.ContentFilterElement{
+			FilterOperator: ua.FilterOperatorGreaterThanOrEqual,
+			FilterOperands: []*ua.ExtensionObject{
+				ua.NewExtensionObject(eventFieldOperand([]*ua.QualifiedName{{Name: "Severity"}})),
+				ua.NewExtensionObject(&ua.LiteralOperand{Value: ua.MustVariant(severity)}),
+			},
+		})
+	}
+
+	switch len(elements) {
+	case 0:
+	case 1:
+		filter.WhereClause = &ua.ContentFilter{Elements: elements}
+	default:
+		// the first element of a content filter is its result
+		and := &ua.ContentFilterElement{FilterOperator: ua.FilterOperatorAnd}
+		for i := range elements {
+			and.FilterOperands = append(and.FilterOperands, ua.NewExtensionObject(&ua.ElementOperand{Index: uint32(i + 1)})) // #nosec G115
+		}
+		filter.WhereClause = &ua.ContentFilter{Elements: append([]*ua.ContentFilterElement{and}, elements...)}
+	}
+	return filter, names, nil
+}
+
+// eventFieldOperand selects a field of the BaseEventType, or of its subtypes
+func eventFieldOperand(path []*ua.QualifiedName) *ua.SimpleAttributeOperand {
+	return &ua.SimpleAttributeOperand{
+		TypeDefinitionID: ua.NewNumericNodeID(0, id.BaseEventType),
+		BrowsePath:       path,
+		AttributeID:      ua.AttributeIDValue,
+	}
+}
+
+// checkEventAttributes checks the event attributes of a resource
+func checkEventAttributes(attrs map[string]interface{}) error {
+	_, _, err := eventFilter(attrs, nil)
+	return err
+}
+
+func (d *Driver) makeEventHistoryRead(deviceName string, deviceClient *opcua.Client, settings deviceSettings, req sdkModel.CommandRequest,
+	nodeID *ua.NodeID, params map[string]interface{}, window historyWindow) (*sdkModel.CommandValue, error) {
+	if req.Type != common.ValueTypeObject {
+		return nil, fmt.Errorf("Driver.handleReadCommands: event history of resource %s requires the %s value type", req.DeviceResourceName, common.ValueTypeObject)
+	}
+	filter, names, err := eventFilter(req.Attributes, params)
+	if err != nil {
+		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
+	}
+	details := &ua.ReadEventDetails{
+		NumValuesPerNode: window.maxValues,
+		StartTime:        window.start,
+		EndTime:          window.end,
+		Filter:           filter,
+	}
+
+	ctx, cancel := d.requestContext(settings)
+	defer cancel()
+	node := &ua.HistoryReadValueID{NodeID: nodeID, DataEncoding: &ua.QualifiedName{}}
+	events, err := historyEvents(ctx, deviceClient, node, historyDetails(details), window.maxValues)
+	if err != nil {
+		if status, ok := err.(ua.StatusCode); ok {
+			d.checkNodeStatus(deviceClient, status)
+		}
+		return nil, fmt.Errorf("Driver.handleReadCommands: HistoryRead failed: %v", err)
+	}
+
+	result, err := newResult(req, eventReading(names, events))
+	if err != nil {
+		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
+	}
+	return result, nil
+}
+
+// historyEvents reads at most limit events from the history of a notifier node, all events when limit is zero
+func historyEvents(ctx context.Context, client *opcua.Client, node *ua.HistoryReadValueID, details *ua.ExtensionObject, limit uint32) ([]*ua.HistoryEventFieldList, error) {
+	var events []*ua.HistoryEventFieldList
+	err := historyRead(ctx, client, node, details, limit, func(data *ua.ExtensionObject) (int, error) {
+		if data == nil || data.Value == nil {
+			return len(events), nil
+		}
+		page, ok := data.Value.(*ua.HistoryEvent)
+		if !ok {
+			return len(events), fmt.Errorf("unexpected history data %T", data.Value)
+		}
+		events = append(events, page.Events...)
+		return len(events), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(events) > int(limit) {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// eventReading returns the Object reading of events, a list of the selected fields of every event
+func eventReading(names []string, events []*ua.HistoryEventFieldList) []interface{} {
+	reading := make([]interface{}, len(events))
+	for i, event := range events {
+		fields := make(map[string]interface{}, len(names))
+		for j, name := range names {
+			var value interface{}
+			if j < len(event.EventFields) && event.EventFields[j] != nil {
+				value = objectValue(event.EventFields[j])
+			}
+			fields[name] = value
+		}
+		reading[i] = fields
+	}
+	return reading
+}
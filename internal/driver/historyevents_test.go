@@ -0,0 +1,118 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_eventFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		attrs     map[string]interface{}
+		wantNames []string
+		wantPaths [][]*ua.QualifiedName
+		wantErr   bool
+	}{
+		{
+			name:      "OK - default fields",
+			attrs:     map[string]interface{}{},
+			wantNames: []string{"EventId", "EventType", "SourceName", "Time", "Message", "Severity"},
+			wantPaths: [][]*ua.QualifiedName{{{Name: "EventId"}}, {{Name: "EventType"}}, {{Name: "SourceName"}}, {{Name: "Time"}}, {{Name: "Message"}}, {{Name: "Severity"}}},
+		},
+		{
+			name:      "OK - nested and namespaced fields",
+			attrs:     map[string]interface{}{EVENTFIELDS: []interface{}{"Time", "ActiveState/Id", "2:Batch"}},
+			wantNames: []string{"Time", "ActiveState/Id", "2:Batch"},
+			wantPaths: [][]*ua.QualifiedName{{{Name: "Time"}}, {{Name: "ActiveState"}, {Name: "Id"}}, {{NamespaceIndex: 2, Name: "Batch"}}},
+		},
+		{name: "NOK - not a list", attrs: map[string]interface{}{EVENTFIELDS: "Time"}, wantErr: true},
+		{name: "NOK - empty list", attrs: map[string]interface{}{EVENTFIELDS: []interface{}{}}, wantErr: true},
+		{name: "NOK - empty element", attrs: map[string]interface{}{EVENTFIELDS: []interface{}{"ActiveState/"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, paths, err := eventFields(tt.attrs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNames, names)
+			assert.Equal(t, tt.wantPaths, paths)
+		})
+	}
+}
+
+func Test_eventFilter(t *testing.T) {
+	tests := []struct {
+		name          string
+		attrs         map[string]interface{}
+		params        map[string]interface{}
+		wantOperators []ua.FilterOperator
+		wantErr       bool
+	}{
+		{name: "OK - all events", attrs: map[string]interface{}{}},
+		{
+			name:          "OK - event type",
+			attrs:         map[string]interface{}{EVENTTYPE: "i=2915"},
+			wantOperators: []ua.FilterOperator{ua.FilterOperatorOfType},
+		},
+		{
+			name:          "OK - event type and severity",
+			attrs:         map[string]interface{}{EVENTTYPE: "i=2915"},
+			params:        map[string]interface{}{"minSeverity": "500"},
+			wantOperators: []ua.FilterOperator{ua.FilterOperatorAnd, ua.FilterOperatorOfType, ua.FilterOperatorGreaterThanOrEqual},
+		},
+		{name: "NOK - invalid event type", attrs: map[string]interface{}{EVENTTYPE: "ns=x;i=1"}, wantErr: true},
+		{name: "NOK - severity out of range", params: map[string]interface{}{"minSeverity": "1001"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, _, err := eventFilter(tt.attrs, tt.params)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, filter.SelectClauses, len(defaultEventFields))
+			if tt.wantOperators == nil {
+				assert.Nil(t, filter.WhereClause)
+				return
+			}
+			require.NotNil(t, filter.WhereClause)
+			operators := make([]ua.FilterOperator, len(filter.WhereClause.Elements))
+			for i, element := range filter.WhereClause.Elements {
+				operators[i] = element.FilterOperator
+			}
+			assert.Equal(t, tt.wantOperators, operators)
+		})
+	}
+}
+
+func Test_eventReading(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	events := []*ua.HistoryEventFieldList{
+		{EventFields: []*ua.Variant{
+			ua.MustVariant(ts),
+			ua.MustVariant(&ua.LocalizedText{Text: "Temperature high"}),
+			ua.MustVariant(uint16(700)),
+		}},
+		{EventFields: []*ua.Variant{ua.MustVariant(ts.Add(time.Minute)), nil}},
+	}
+
+	got := eventReading([]string{"Time", "Message", "Severity"}, events)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"Time": ts, "Message": "Temperature high", "Severity": uint16(700)},
+		map[string]interface{}{"Time": ts.Add(time.Minute), "Message": nil, "Severity": nil},
+	}, got)
+}
@@ -0,0 +1,602 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+	"github.com/spf13/cast"
+)
+
+const (
+	inputArgumentsProperty  = "InputArguments"
+	outputArgumentsProperty = "OutputArguments"
+	valueRankScalar         = -1
+)
+
+// methodArguments reads the InputArguments or OutputArguments property of a method.
+// Methods without the property have no arguments of this kind.
+func methodArguments(ctx context.Context, client *opcua.Client, methodID *ua.NodeID, property string) ([]*ua.Argument, error) {
+	propertyID, err := client.Node(methodID).TranslateBrowsePathsToNodeIDs(ctx, []*ua.QualifiedName{{Name: property}})
+	if err != nil {
+		if err == ua.StatusBadNoMatch {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to find %s of method %s: %v", property, methodID, err)
+	}
+
+	value, err := client.Node(propertyID).Value(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s of method %s: %v", property, methodID, err)
+	}
+	if value == nil {
+		// the property is declared without a value, so the method takes no such arguments
+		return []*ua.Argument{}, nil
+	}
+	eos, _ := value.Value().([]*ua.ExtensionObject)
+	args := make([]*ua.Argument, 0, len(eos))
+	for _, eo := range eos {
+		arg, ok := eo.Value.(*ua.Argument)
+		if !ok {
+			return nil, fmt.Errorf("%s of method %s holds %T instead of Argument", property, methodID, eo.Value)
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// methodSignature holds the arguments of a method as declared by the server
+type methodSignature struct {
+	inputs  []*ua.Argument
+	outputs []*ua.Argument
+	// described is false when the method has no InputArguments property
+	described bool
+}
+
+// methodSignature returns the arguments of a method, read once per device and cached
+func (d *Driver) methodSignature(deviceName string, client *opcua.Client, methodID *ua.NodeID) (*methodSignature, error) {
+	key := deviceName + "/" + methodID.String()
+	if sig, ok := d.cachedMethodSignature(key); ok {
+		return sig, nil
+	}
+
+	ctx := d.serviceContext()
+	inputs, err := methodArguments(ctx, client, methodID, inputArgumentsProperty)
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := methodArguments(ctx, client, methodID, outputArgumentsProperty)
+	if err != nil {
+		return nil, err
+	}
+	sig := &methodSignature{inputs: inputs, outputs: outputs, described: inputs != nil}
+
+	d.methodsMu.Lock()
+	defer d.methodsMu.Unlock()
+	if d.methods == nil {
+		d.methods = make(map[string]*methodSignature)
+	}
+	d.methods[key] = sig
+	return sig, nil
+}
+
+func (d *Driver) cachedMethodSignature(key string) (*methodSignature, bool) {
+	d.methodsMu.Lock()
+	defer d.methodsMu.Unlock()
+	sig, ok := d.methods[key]
+	return sig, ok
+}
+
+// forgetMethods drops the cached method signatures of a device
+func (d *Driver) forgetMethods(deviceName string) {
+	d.methodsMu.Lock()
+	defer d.methodsMu.Unlock()
+	for key := range d.methods {
+		if strings.HasPrefix(key, deviceName+"/") {
+			delete(d.methods, key)
+		}
+	}
+}
+
+// methodInputs builds the typed input arguments of a method call and returns them with
+// their names. The value of each argument is taken from the parameter of the same name,
+// or from the inputMap by position.
+func (d *Driver) methodInputs(deviceName string, client *opcua.Client, methodID *ua.NodeID, inputMap []interface{}, params map[string]interface{}) ([]*ua.Variant, []string, error) {
+	sig, err := d.methodSignature(deviceName, client, methodID)
+	if err != nil {
+		return nil, nil, err
+	}
+	args := sig.inputs
+
+	if !sig.described {
+		// the server does not describe the arguments, send the values as they are
+		inputs := make([]*ua.Variant, len(inputMap))
+		names := make([]string, len(inputMap))
+		for i, value := range inputMap {
+			names[i] = argumentName(nil, i)
+			inputs[i], err = ua.NewVariant(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("input argument %s: %v", names[i], err)
+			}
+		}
+		return inputs, names, nil
+	}
+
+	values, err := methodInputValues(args, inputMap, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validateInputValues(args, values); err != nil {
+		return nil, nil, err
+	}
+
+	ctx := d.serviceContext()
+	inputs := make([]*ua.Variant, len(args))
+	names := make([]string, len(args))
+	for i, arg := range args {
+		names[i] = arg.Name
+		def, err := d.dataTypeDefinition(ctx, deviceName, client, arg.DataType, 0)
+		if err != nil {
+			return nil, nil, fmt.Errorf("input argument %s: %v", arg.Name, err)
+		}
+		inputs[i], err = argumentVariant(def, arg.ValueRank, values[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("input argument %s expects %s: %v", arg.Name, argumentTypeName(arg), err)
+		}
+	}
+	return inputs, names, nil
+}
+
+// callMethod calls a method with the inputMap and the parameters as input arguments
+func (d *Driver) callMethod(deviceName string, client *opcua.Client, settings deviceSettings, objectID, methodID *ua.NodeID, inputMap []interface{}, params map[string]interface{}) (*ua.CallMethodResult, error) {
+	inputs, inputNames, err := d.methodInputs(deviceName, client, methodID, inputMap, params)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &ua.CallMethodRequest{
+		ObjectID:       objectID,
+		MethodID:       methodID,
+		InputArguments: inputs,
+	}
+
+	ctx, cancel := d.requestContext(settings)
+	defer cancel()
+	resp, err := client.Call(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("Method call failed: %s", err)
+	}
+	if !isGood(resp.StatusCode) {
+		d.checkNodeStatus(client, resp.StatusCode)
+		return nil, methodCallError(resp, inputNames)
+	}
+	return resp, nil
+}
+
+// methodOutputs returns the OutputArguments of a method. The data types of the outputs are
+// resolved for Object readings, so that structures returned by the method can be decoded.
+func (d *Driver) methodOutputs(deviceName string, client *opcua.Client, methodID *ua.NodeID, valueType string) ([]*ua.Argument, error) {
+	sig, err := d.methodSignature(deviceName, client, methodID)
+	if err != nil {
+		return nil, err
+	}
+	if valueType != common.ValueTypeObject {
+		return sig.outputs, nil
+	}
+
+	ctx := d.serviceContext()
+	for _, arg := range sig.outputs {
+		if _, err := d.dataTypeDefinition(ctx, deviceName, client, arg.DataType, 0); err != nil {
+			return nil, fmt.Errorf("output argument %s: %v", arg.Name, err)
+		}
+	}
+	return sig.outputs, nil
+}
+
+// validateInputValues checks the number of input values and whether each value is a list
+// where the argument expects an array and a single value where it expects a scalar.
+// Values may be missing at the end, when they are supplied with the command.
+func validateInputValues(args []*ua.Argument, values []interface{}) error {
+	if len(values) > len(args) {
+		return fmt.Errorf("method takes %d input arguments, %d values given", len(args), len(values))
+	}
+
+	for i, value := range values {
+		arg := args[i]
+		array, isArray := value.([]interface{})
+		switch {
+		case arg.ValueRank == valueRankScalar && isArray:
+			return fmt.Errorf("input argument %s expects a single %s, not a list", arg.Name, argumentTypeName(arg))
+		case arg.ValueRank >= 0 && !isArray:
+			return fmt.Errorf("input argument %s expects a list of %s", arg.Name, argumentTypeName(arg))
+		case arg.ValueRank == 1 && len(arg.ArrayDimensions) == 1 && arg.ArrayDimensions[0] > 0 &&
+			uint32(len(array)) > arg.ArrayDimensions[0]:
+			return fmt.Errorf("input argument %s takes at most %d values, %d given", arg.Name, arg.ArrayDimensions[0], len(array))
+		}
+	}
+	return nil
+}
+
+// argumentTypeName returns the name of the data type of an argument
+func argumentTypeName(arg *ua.Argument) string {
+	if arg.DataType == nil {
+		return "Variant"
+	}
+	if arg.DataType.Namespace() == 0 && arg.DataType.Type() == ua.NodeIDTypeNumeric {
+		if typeID := ua.TypeID(arg.DataType.IntID()); typeID >= ua.TypeIDBoolean && typeID <= ua.TypeIDDiagnosticInfo {
+			return strings.TrimPrefix(typeID.String(), "TypeID")
+		}
+	}
+	return arg.DataType.String()
+}
+
+// methodAttributes returns the object, the method and the inputMap of a method resource
+func (d *Driver) methodAttributes(deviceName string, client *opcua.Client, attrs map[string]interface{}) (*ua.NodeID, *ua.NodeID, []interface{}, error) {
+	oid, err := d.resourceNodeID(deviceName, client, attrs, OBJECT)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	mid, err := d.resourceNodeID(deviceName, client, attrs, METHOD)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	inputMap, err := getInputMap(attrs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return oid, mid, inputMap, nil
+}
+
+// validateResources checks the node attributes of the resources in the profile of a device.
+// The inputMap of methods is also checked against their arguments, read from the server when
+// it is reachable.
+func (d *Driver) validateResources(device models.Device) error {
+	if d.sdkService == nil {
+		return nil
+	}
+	profile, err := d.sdkService.GetProfileByName(device.ProfileName)
+	if err != nil {
+		d.Logger.Debugf("Unable to validate the resources of device %s: %v", device.Name, err)
+		return nil
+	}
+
+	// connected once a method signature is needed
+	var client *opcua.Client
+	connected := false
+	for _, resource := range profile.DeviceResources {
+		if _, ok := resource.Attributes[METHOD]; !ok {
+			if _, ok := resource.Attributes[BROWSEPATH]; ok {
+				if err := checkNodeAttribute(resource.Attributes, NODE); err != nil {
+					return fmt.Errorf("resource %s: %v", resource.Name, err)
+				}
+			}
+			if _, ok := resource.Attributes[HISTORY]; ok {
+				if err := checkHistoryAttributes(resource.Attributes); err != nil {
+					return fmt.Errorf("resource %s: %v", resource.Name, err)
+				}
+			}
+			if err := checkEncoding(resource.Attributes); err != nil {
+				return fmt.Errorf("resource %s: %v", resource.Name, err)
+			}
+			continue
+		}
+
+		for _, id := range []string{OBJECT, METHOD} {
+			if err := checkNodeAttribute(resource.Attributes, id); err != nil {
+				return fmt.Errorf("resource %s: %v", resource.Name, err)
+			}
+		}
+		inputMap, err := getInputMap(resource.Attributes)
+		if err != nil {
+			return fmt.Errorf("resource %s: %v", resource.Name, err)
+		}
+		if len(inputMap) == 0 {
+			continue
+		}
+
+		if !connected {
+			connected = true
+			client = d.validationClient(device)
+		}
+		sig, err := d.resourceMethodSignature(device.Name, client, resource.Attributes)
+		if err != nil {
+			d.Logger.Warnf("Unable to check the %s of resource %s: %v", INPUTMAP, resource.Name, err)
+			continue
+		}
+		if sig == nil || !sig.described {
+			continue
+		}
+		if err := validateInputValues(sig.inputs, inputMap); err != nil {
+			return fmt.Errorf("resource %s: %v", resource.Name, err)
+		}
+	}
+	return nil
+}
+
+// validationClient returns the client of a device used to read method signatures while
+// validating it, or nil when the server is not reachable
+func (d *Driver) validationClient(device models.Device) *opcua.Client {
+	endpoint, xerr := FetchEndpoint(device.Protocols)
+	if xerr != nil {
+		return nil
+	}
+	settings, xerr := fetchDeviceSettings(device.Protocols)
+	if xerr != nil {
+		return nil
+	}
+	client, err := d.buildClient(device.Name, endpoint, settings)
+	if err != nil {
+		d.Logger.Debugf("Unable to read the method signatures of device %s: %v", device.Name, err)
+		return nil
+	}
+	return client
+}
+
+// resourceMethodSignature returns the arguments of the method of a resource, the cached ones
+// when the client is nil. A nil signature is returned when they are not known.
+func (d *Driver) resourceMethodSignature(deviceName string, client *opcua.Client, attrs map[string]interface{}) (*methodSignature, error) {
+	if client == nil {
+		mid, ok := d.cachedNodeID(deviceName, attrs, METHOD)
+		if !ok {
+			return nil, nil
+		}
+		sig, _ := d.cachedMethodSignature(deviceName + "/" + mid.String())
+		return sig, nil
+	}
+	mid, err := d.resourceNodeID(deviceName, client, attrs, METHOD)
+	if err != nil {
+		return nil, err
+	}
+	return d.methodSignature(deviceName, client, mid)
+}
+
+// argumentName returns the name of the argument at position i, or a name
+// derived from the position when the server does not describe the arguments
+func argumentName(args []*ua.Argument, i int) string {
+	if i < len(args) && args[i].Name != "" {
+		return args[i].Name
+	}
+	return fmt.Sprintf("argument%d", i+1)
+}
+
+// methodCallError reports a failed method call, naming the rejected input arguments
+func methodCallError(result *ua.CallMethodResult, inputNames []string) error {
+	var rejected []string
+	for i, status := range result.InputArgumentResults {
+		if status == ua.StatusOK {
+			continue
+		}
+		name := argumentName(nil, i)
+		if i < len(inputNames) {
+			name = inputNames[i]
+		}
+		rejected = append(rejected, fmt.Sprintf("%s: %s", name, statusName(status)))
+	}
+
+	if len(rejected) == 0 {
+		return fmt.Errorf("method status not Good: %s", statusName(result.StatusCode))
+	}
+	return fmt.Errorf("method status not Good: %s; input arguments rejected: %s", statusName(result.StatusCode), strings.Join(rejected, ", "))
+}
+
+// methodResult creates the reading of the output arguments of a method call. Object readings
+// map the output names to their values and array readings list the values of all outputs.
+// Other value types take the single output of the method, or the call status code when the
+// method has no outputs.
+func (d *Driver) methodResult(deviceName string, req sdkModel.CommandRequest, result *ua.CallMethodResult, outputArgs []*ua.Argument) (*sdkModel.CommandValue, error) {
+	outputs := result.OutputArguments
+
+	if req.Type == common.ValueTypeObject {
+		values := make(map[string]interface{}, len(outputs))
+		for i, output := range outputs {
+			value, err := d.structureReading(deviceName, output.Value())
+			if err != nil {
+				return nil, fmt.Errorf("output argument %s: %v", argumentName(outputArgs, i), err)
+			}
+			values[argumentName(outputArgs, i)] = objectValue(value)
+		}
+		return newResult(req, values)
+	}
+
+	if _, ok := arrayElementTypes[req.Type]; ok && len(outputs) != 1 {
+		values := make([]interface{}, len(outputs))
+		for i, output := range outputs {
+			values[i] = output.Value()
+		}
+		return newResult(req, values)
+	}
+
+	switch len(outputs) {
+	case 0:
+		return newResult(req, result.StatusCode)
+	case 1:
+		return newResult(req, outputs[0].Value())
+	}
+	return nil, fmt.Errorf("method returns %d output arguments, use the %s value type or an array value type", len(outputs), common.ValueTypeObject)
+}
+
+// methodWriteParameters returns the input arguments given by the value of a set command.
+// An object whose keys are all input argument names sets these arguments, any other value
+// sets the first input argument. Methods without input arguments ignore the value.
+func methodWriteParameters(args []*ua.Argument, value interface{}) (map[string]interface{}, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	if object, ok := value.(map[string]interface{}); ok && len(object) > 0 {
+		named := true
+		for name := range object {
+			if !hasArgument(args, name) {
+				named = false
+				break
+			}
+		}
+		if named {
+			return object, nil
+		}
+	}
+
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		elements, err := flattenArray(v)
+		if err != nil {
+			return nil, err
+		}
+		value = elements
+	}
+	return map[string]interface{}{args[0].Name: value}, nil
+}
+
+func hasArgument(args []*ua.Argument, name string) bool {
+	for _, arg := range args {
+		if arg.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// methodInputValues assigns the parameters and the inputMap values to the input arguments
+func methodInputValues(args []*ua.Argument, inputMap []interface{}, params map[string]interface{}) ([]interface{}, error) {
+	if len(inputMap) > len(args) {
+		return nil, fmt.Errorf("method takes %d input arguments, inputMap has %d values", len(args), len(inputMap))
+	}
+
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		if value, ok := params[arg.Name]; ok {
+			values[i] = value
+		} else if i < len(inputMap) {
+			values[i] = inputMap[i]
+		} else {
+			return nil, fmt.Errorf("no value for input argument %s", arg.Name)
+		}
+	}
+	return values, nil
+}
+
+// argumentVariant converts a value into the data type and value rank of a method argument
+func argumentVariant(def *dataTypeDefinition, valueRank int32, value interface{}) (*ua.Variant, error) {
+	array, isArray := value.([]interface{})
+	if !isArray || valueRank == valueRankScalar {
+		v, err := argumentValue(def, value)
+		if err != nil {
+			return nil, err
+		}
+		return ua.NewVariant(v)
+	}
+
+	if len(array) == 0 {
+		// the type of an empty array is taken from the zero value of the data type
+		zero, err := argumentValue(def, nil)
+		if err != nil {
+			return nil, fmt.Errorf("empty array of %s is not supported", def.dataTypeID)
+		}
+		return ua.NewVariant(reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(zero)), 0, 0).Interface())
+	}
+
+	var values reflect.Value
+	for i, element := range array {
+		v, err := argumentValue(def, element)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %v", i, err)
+		}
+		if i == 0 {
+			values = reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(v)), len(array), len(array))
+		}
+		values.Index(i).Set(reflect.ValueOf(v))
+	}
+	return ua.NewVariant(values.Interface())
+}
+
+// argumentValue converts a scalar value into the data type of a method argument
+func argumentValue(def *dataTypeDefinition, value interface{}) (interface{}, error) {
+	if def.structure != nil && def.builtin == ua.TypeIDExtensionObject {
+		return newStructureExtensionObject(def, value)
+	}
+	if def.enumStrings != nil {
+		var err error
+		if value, err = enumWriteValue(def, value); err != nil {
+			return nil, err
+		}
+	}
+
+	switch def.builtin {
+	case ua.TypeIDBoolean:
+		return cast.ToBoolE(value)
+	case ua.TypeIDSByte:
+		return cast.ToInt8E(value)
+	case ua.TypeIDByte:
+		return cast.ToUint8E(value)
+	case ua.TypeIDInt16:
+		return cast.ToInt16E(value)
+	case ua.TypeIDUint16:
+		return cast.ToUint16E(value)
+	case ua.TypeIDInt32:
+		return cast.ToInt32E(value)
+	case ua.TypeIDUint32:
+		return cast.ToUint32E(value)
+	case ua.TypeIDInt64:
+		return cast.ToInt64E(value)
+	case ua.TypeIDUint64:
+		return cast.ToUint64E(value)
+	case ua.TypeIDFloat:
+		return cast.ToFloat32E(value)
+	case ua.TypeIDDouble:
+		return cast.ToFloat64E(value)
+	case ua.TypeIDString:
+		return cast.ToStringE(value)
+	case ua.TypeIDStatusCode:
+		code, err := cast.ToUint32E(value)
+		return ua.StatusCode(code), err
+	case ua.TypeIDDateTime:
+		if v, ok := value.(string); ok {
+			return builtinWriteValue(def.builtin, v)
+		}
+		return cast.ToTimeE(value)
+	case ua.TypeIDByteString:
+		return byteStringValue(value)
+	case ua.TypeIDGUID, ua.TypeIDLocalizedText, ua.TypeIDQualifiedName, ua.TypeIDNodeID,
+		ua.TypeIDExpandedNodeID, ua.TypeIDXMLElement:
+		text, err := cast.ToStringE(value)
+		if err != nil {
+			return nil, err
+		}
+		return builtinWriteValue(def.builtin, text)
+	case ua.TypeIDVariant:
+		return value, nil
+	}
+	return nil, fmt.Errorf("data type %s is not supported for method arguments", def.dataTypeID)
+}
+
+// queryParameters returns the query parameters of a command, which the SDK passes
+// in the urlRawQuery attribute
+func queryParameters(attrs map[string]interface{}) map[string]interface{} {
+	rawQuery, ok := attrs[URLRawQuery].(string)
+	if !ok {
+		return nil
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil
+	}
+
+	params := make(map[string]interface{}, len(query))
+	for name, values := range query {
+		params[name] = values[0]
+	}
+	return params
+}
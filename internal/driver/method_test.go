@@ -0,0 +1,331 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"testing"
+
+	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_methodInputValues(t *testing.T) {
+	args := []*ua.Argument{
+		{Name: "Speed", DataType: ua.NewNumericNodeID(0, uint32(ua.TypeIDDouble)), ValueRank: -1},
+		{Name: "Ramp", DataType: ua.NewNumericNodeID(0, uint32(ua.TypeIDUint32)), ValueRank: -1},
+	}
+
+	tests := []struct {
+		name     string
+		inputMap []interface{}
+		params   map[string]interface{}
+		want     []interface{}
+		wantErr  bool
+	}{
+		{name: "OK - inputMap", inputMap: []interface{}{12.5, 10}, want: []interface{}{12.5, 10}},
+		{name: "OK - parameters", params: map[string]interface{}{"Speed": "20", "Ramp": "5"}, want: []interface{}{"20", "5"}},
+		{name: "OK - parameter overrides inputMap", inputMap: []interface{}{12.5, 10}, params: map[string]interface{}{"Ramp": "5"}, want: []interface{}{12.5, "5"}},
+		{name: "NOK - missing argument", inputMap: []interface{}{12.5}, wantErr: true},
+		{name: "NOK - too many values", inputMap: []interface{}{12.5, 10, 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := methodInputValues(args, tt.inputMap, tt.params)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_argumentVariant(t *testing.T) {
+	tests := []struct {
+		name      string
+		def       *dataTypeDefinition
+		valueRank int32
+		value     interface{}
+		want      interface{}
+		wantErr   bool
+	}{
+		{name: "OK - Double from string", def: builtinDefinition(ua.TypeIDDouble), valueRank: -1, value: "12.5", want: 12.5},
+		{name: "OK - Double from integer", def: builtinDefinition(ua.TypeIDDouble), valueRank: -1, value: 12, want: 12.0},
+		{name: "OK - Byte", def: builtinDefinition(ua.TypeIDByte), valueRank: -1, value: 7, want: uint8(7)},
+		{name: "OK - Boolean", def: builtinDefinition(ua.TypeIDBoolean), valueRank: -1, value: "true", want: true},
+		{name: "OK - String from number", def: builtinDefinition(ua.TypeIDString), valueRank: -1, value: 5, want: "5"},
+		{name: "OK - NodeId", def: builtinDefinition(ua.TypeIDNodeID), valueRank: -1, value: "ns=2;i=5", want: ua.MustParseNodeID("ns=2;i=5")},
+		{name: "OK - enumeration", def: machineStateDefinition(), valueRank: -1, value: "Running", want: int32(1)},
+		{name: "OK - array", def: builtinDefinition(ua.TypeIDInt16), valueRank: 1, value: []interface{}{1, "2"}, want: []int16{1, 2}},
+		{name: "OK - empty array", def: builtinDefinition(ua.TypeIDFloat), valueRank: 1, value: []interface{}{}, want: []float32{}},
+		{name: "NOK - invalid number", def: builtinDefinition(ua.TypeIDInt32), valueRank: -1, value: "fast", wantErr: true},
+		{name: "NOK - array for a scalar", def: builtinDefinition(ua.TypeIDInt32), valueRank: -1, value: []interface{}{1}, wantErr: true},
+		{name: "NOK - invalid array element", def: builtinDefinition(ua.TypeIDInt32), valueRank: 1, value: []interface{}{1, "x"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := argumentVariant(tt.def, tt.valueRank, tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Value())
+		})
+	}
+}
+
+func Test_queryParameters(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs map[string]interface{}
+		want  map[string]interface{}
+	}{
+		{"no query", map[string]interface{}{}, nil},
+		{"parameters", map[string]interface{}{URLRawQuery: "Speed=12.5&Ramp=5&Ramp=6"}, map[string]interface{}{"Speed": "12.5", "Ramp": "5"}},
+		{"invalid query", map[string]interface{}{URLRawQuery: "Speed=%zz"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, queryParameters(tt.attrs))
+		})
+	}
+}
+
+func Test_methodCallError(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     *ua.CallMethodResult
+		inputNames []string
+		want       string
+	}{
+		{
+			name:   "method status",
+			result: &ua.CallMethodResult{StatusCode: ua.StatusBadNotExecutable},
+			want:   "method status not Good: BadNotExecutable",
+		},
+		{
+			name: "rejected input arguments",
+			result: &ua.CallMethodResult{
+				StatusCode:           ua.StatusBadInvalidArgument,
+				InputArgumentResults: []ua.StatusCode{ua.StatusOK, ua.StatusBadTypeMismatch, ua.StatusBadOutOfRange},
+			},
+			inputNames: []string{"Speed", "Ramp"},
+			want:       "method status not Good: BadInvalidArgument; input arguments rejected: Ramp: BadTypeMismatch, argument3: BadOutOfRange",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, methodCallError(tt.result, tt.inputNames), tt.want)
+		})
+	}
+}
+
+func TestDriver_methodResult(t *testing.T) {
+	outputArgs := []*ua.Argument{{Name: "Speed"}, {Name: "Running"}}
+	twoOutputs := &ua.CallMethodResult{OutputArguments: []*ua.Variant{ua.MustVariant(12.5), ua.MustVariant(true)}}
+	oneOutput := &ua.CallMethodResult{OutputArguments: []*ua.Variant{ua.MustVariant(12.5)}}
+	noOutput := &ua.CallMethodResult{StatusCode: ua.StatusOK}
+
+	tests := []struct {
+		name       string
+		valueType  string
+		result     *ua.CallMethodResult
+		outputArgs []*ua.Argument
+		want       interface{}
+		wantErr    bool
+	}{
+		{name: "OK - outputs keyed by name", valueType: common.ValueTypeObject, result: twoOutputs, outputArgs: outputArgs, want: map[string]interface{}{"Speed": 12.5, "Running": true}},
+		{name: "OK - outputs without names", valueType: common.ValueTypeObject, result: twoOutputs, want: map[string]interface{}{"argument1": 12.5, "argument2": true}},
+		{name: "OK - outputs as array", valueType: common.ValueTypeStringArray, result: twoOutputs, want: []string{"12.5", "true"}},
+		{name: "OK - single output", valueType: common.ValueTypeFloat64, result: oneOutput, want: 12.5},
+		{name: "OK - no output returns the status", valueType: common.ValueTypeString, result: noOutput, want: "Good"},
+		{name: "OK - no output as object", valueType: common.ValueTypeObject, result: noOutput, want: map[string]interface{}{}},
+		{name: "NOK - several outputs for a scalar", valueType: common.ValueTypeFloat64, result: twoOutputs, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Driver{}
+			req := sdkModel.CommandRequest{DeviceResourceName: "Method", Type: tt.valueType}
+			got, err := d.methodResult("Test", req, tt.result, tt.outputArgs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Value)
+		})
+	}
+}
+
+func Test_validateInputValues(t *testing.T) {
+	args := []*ua.Argument{
+		{Name: "Speed", DataType: ua.NewNumericNodeID(0, uint32(ua.TypeIDDouble)), ValueRank: -1},
+		{Name: "Setpoints", DataType: ua.NewNumericNodeID(0, uint32(ua.TypeIDInt16)), ValueRank: 1, ArrayDimensions: []uint32{3}},
+		{Name: "Payload", DataType: ua.NewNumericNodeID(2, 3001), ValueRank: -2},
+	}
+
+	tests := []struct {
+		name    string
+		values  []interface{}
+		wantErr string
+	}{
+		{name: "OK - all values", values: []interface{}{12.5, []interface{}{1, 2}, "any"}},
+		{name: "OK - missing values", values: []interface{}{12.5}},
+		{name: "OK - any value rank", values: []interface{}{12.5, []interface{}{}, []interface{}{1}}},
+		{name: "NOK - too many values", values: []interface{}{12.5, []interface{}{}, 1, 2}, wantErr: "method takes 3 input arguments, 4 values given"},
+		{name: "NOK - list for a scalar", values: []interface{}{[]interface{}{12.5}}, wantErr: "input argument Speed expects a single Double, not a list"},
+		{name: "NOK - scalar for an array", values: []interface{}{12.5, 1}, wantErr: "input argument Setpoints expects a list of Int16"},
+		{name: "NOK - array too long", values: []interface{}{12.5, []interface{}{1, 2, 3, 4}}, wantErr: "input argument Setpoints takes at most 3 values, 4 given"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateInputValues(args, tt.values)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_argumentTypeName(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  *ua.Argument
+		want string
+	}{
+		{"built-in type", &ua.Argument{DataType: ua.NewNumericNodeID(0, uint32(ua.TypeIDDouble))}, "Double"},
+		{"server type", &ua.Argument{DataType: ua.NewNumericNodeID(2, 3001)}, "ns=2;i=3001"},
+		{"standard structure", &ua.Argument{DataType: ua.NewNumericNodeID(0, 884)}, "i=884"},
+		{"no data type", &ua.Argument{}, "Variant"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, argumentTypeName(tt.arg))
+		})
+	}
+}
+
+func Test_methodAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   map[string]interface{}
+		wantErr bool
+	}{
+		{name: "OK - method", attrs: map[string]interface{}{OBJECT: "ns=2;i=1", METHOD: "ns=2;i=2", INPUTMAP: []interface{}{1}}},
+		{name: "NOK - missing object", attrs: map[string]interface{}{METHOD: "ns=2;i=2"}, wantErr: true},
+		{name: "NOK - invalid object", attrs: map[string]interface{}{OBJECT: "ns=x;i=1", METHOD: "ns=2;i=2"}, wantErr: true},
+		{name: "NOK - invalid method", attrs: map[string]interface{}{OBJECT: "ns=2;i=1", METHOD: "ns=2;i=x"}, wantErr: true},
+		{name: "NOK - invalid inputMap", attrs: map[string]interface{}{OBJECT: "ns=2;i=1", METHOD: "ns=2;i=2", INPUTMAP: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Driver{}
+			oid, mid, inputMap, err := d.methodAttributes("Test", nil, tt.attrs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "ns=2;i=1", oid.String())
+			assert.Equal(t, "ns=2;i=2", mid.String())
+			assert.Equal(t, []interface{}{1}, inputMap)
+		})
+	}
+}
+
+func TestDriver_forgetMethods(t *testing.T) {
+	d := &Driver{methods: map[string]*methodSignature{
+		"Test/ns=2;i=2":  {},
+		"Test2/ns=2;i=2": {},
+	}}
+	d.forgetMethods("Test")
+	_, ok := d.cachedMethodSignature("Test/ns=2;i=2")
+	assert.False(t, ok)
+	_, ok = d.cachedMethodSignature("Test2/ns=2;i=2")
+	assert.True(t, ok)
+}
+
+func Test_methodWriteParameters(t *testing.T) {
+	args := []*ua.Argument{{Name: "Speed"}, {Name: "Ramp"}}
+	setpoint := []*ua.Argument{{Name: "Setpoint"}}
+
+	tests := []struct {
+		name  string
+		args  []*ua.Argument
+		value interface{}
+		want  map[string]interface{}
+	}{
+		{name: "scalar sets the first argument", args: args, value: 12.5, want: map[string]interface{}{"Speed": 12.5}},
+		{name: "object sets arguments by name", args: args, value: map[string]interface{}{"Ramp": 5.0}, want: map[string]interface{}{"Ramp": 5.0}},
+		{name: "structure sets the first argument", args: setpoint, value: map[string]interface{}{"Value": 5.0}, want: map[string]interface{}{"Setpoint": map[string]interface{}{"Value": 5.0}}},
+		{name: "array is passed as a list", args: setpoint, value: []float64{1, 2}, want: map[string]interface{}{"Setpoint": []interface{}{1.0, 2.0}}},
+		{name: "binary is passed as bytes", args: setpoint, value: []byte{1, 2}, want: map[string]interface{}{"Setpoint": []byte{1, 2}}},
+		{name: "method without input arguments", value: true, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := methodWriteParameters(tt.args, tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDriver_validateResources(t *testing.T) {
+	profile := func(inputMap []interface{}) models.DeviceProfile {
+		return models.DeviceProfile{Name: "Machine", DeviceResources: []models.DeviceResource{{
+			Name:       "SetSpeed",
+			Attributes: map[string]interface{}{OBJECT: "ns=2;s=Machine", METHOD: "ns=2;s=SetSpeed", INPUTMAP: inputMap},
+		}}}
+	}
+	args := []*ua.Argument{{Name: "Speed", DataType: ua.NewNumericNodeID(0, uint32(ua.TypeIDDouble)), ValueRank: -1}}
+
+	tests := []struct {
+		name     string
+		inputMap []interface{}
+		sig      *methodSignature
+		wantErr  bool
+	}{
+		{name: "OK - inputMap matching the arguments", inputMap: []interface{}{1.5}, sig: &methodSignature{inputs: args, described: true}},
+		{name: "OK - arguments not known", inputMap: []interface{}{1.5, 2}},
+		{name: "OK - arguments not described", inputMap: []interface{}{1.5, 2}, sig: &methodSignature{}},
+		{name: "NOK - too many values", inputMap: []interface{}{1.5, 2}, sig: &methodSignature{inputs: args, described: true}, wantErr: true},
+		{name: "NOK - list for a scalar", inputMap: []interface{}{[]interface{}{1.5}}, sig: &methodSignature{inputs: args, described: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Driver{
+				Logger:     &logger.MockLogger{},
+				clientMap:  map[clientKey]*opcua.Client{},
+				sdkService: &deviceServiceSDK{profiles: map[string]models.DeviceProfile{"Machine": profile(tt.inputMap)}},
+			}
+			if tt.sig != nil {
+				d.methods = map[string]*methodSignature{"Test/ns=2;s=SetSpeed": tt.sig}
+			}
+			// the server is not reachable, the signatures read before are used
+			device := models.Device{Name: "Test", ProfileName: "Machine", Protocols: map[string]models.ProtocolProperties{
+				Protocol: {Endpoint: "opc.tcp://127.0.0.1:1", RequestTimeout: "1s"},
+			}}
+			err := d.validateResources(device)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"sync"
+
+	"github.com/gopcua/opcua"
+)
+
+// monitoredItem is the device resource monitored by an item of a subscription
+type monitoredItem struct {
+	deviceName   string
+	resourceName string
+}
+
+// monitoredItemKey identifies a monitored item by its subscription and client handle. The
+// subscription is kept rather than its id, which changes when the client recreates it after
+// a reconnect.
+type monitoredItemKey struct {
+	sub    *opcua.Subscription
+	handle uint32
+}
+
+// monitoredItemRegistry maps the client handles of monitored items to the device resources
+// they monitor. Handles are unique across all subscriptions of the driver.
+type monitoredItemRegistry struct {
+	mu         sync.Mutex
+	lastHandle uint32
+	items      map[monitoredItemKey]monitoredItem
+	handles    map[uint32]bool
+}
+
+// add registers the item monitoring a device resource in a subscription, and returns its client handle
+func (r *monitoredItemRegistry) add(sub *opcua.Subscription, deviceName, resourceName string) uint32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.items == nil {
+		r.items = make(map[monitoredItemKey]monitoredItem)
+		r.handles = make(map[uint32]bool)
+	}
+
+	// skip the handles still in use when the counter wraps around, and 0
+	handle := r.lastHandle + 1
+	for handle == 0 || r.handles[handle] {
+		handle++
+	}
+	r.lastHandle = handle
+	r.handles[handle] = true
+	r.items[monitoredItemKey{sub: sub, handle: handle}] = monitoredItem{deviceName: deviceName, resourceName: resourceName}
+	return handle
+}
+
+// lookup returns the device resource monitored by the item of a subscription with a client handle
+func (r *monitoredItemRegistry) lookup(sub *opcua.Subscription, handle uint32) (monitoredItem, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item, ok := r.items[monitoredItemKey{sub: sub, handle: handle}]
+	return item, ok
+}
+
+// remove drops the item of a subscription with a client handle
+func (r *monitoredItemRegistry) remove(sub *opcua.Subscription, handle uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := monitoredItemKey{sub: sub, handle: handle}
+	if _, ok := r.items[key]; ok {
+		delete(r.items, key)
+		delete(r.handles, handle)
+	}
+}
+
+// removeSubscription drops all items of a subscription
+func (r *monitoredItemRegistry) removeSubscription(sub *opcua.Subscription) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key := range r.items {
+		if key.sub == sub {
+			delete(r.items, key)
+			delete(r.handles, key.handle)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gopcua/opcua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_monitoredItemRegistry(t *testing.T) {
+	var r monitoredItemRegistry
+	first := &opcua.Subscription{SubscriptionID: 1}
+	second := &opcua.Subscription{SubscriptionID: 1}
+
+	speed := r.add(first, "Press", "Speed")
+	counter := r.add(first, "Press", "Counter")
+	other := r.add(second, "Lathe", "Speed")
+	assert.NotEqual(t, speed, counter)
+	assert.NotEqual(t, counter, other, "handles are unique across subscriptions")
+
+	item, ok := r.lookup(second, other)
+	require.True(t, ok)
+	assert.Equal(t, monitoredItem{deviceName: "Lathe", resourceName: "Speed"}, item)
+	_, ok = r.lookup(second, speed)
+	assert.False(t, ok, "handles are looked up per subscription, even with the same id")
+
+	r.remove(first, counter)
+	_, ok = r.lookup(first, counter)
+	assert.False(t, ok)
+
+	r.removeSubscription(first)
+	_, ok = r.lookup(first, speed)
+	assert.False(t, ok)
+	_, ok = r.lookup(second, other)
+	assert.True(t, ok, "items of other subscriptions are kept")
+}
+
+func Test_monitoredItemRegistry_wrapAround(t *testing.T) {
+	sub := &opcua.Subscription{}
+	r := monitoredItemRegistry{lastHandle: math.MaxUint32 - 1}
+
+	last := r.add(sub, "Press", "Speed")
+	assert.Equal(t, uint32(math.MaxUint32), last)
+	r.lastHandle = 0
+	r.handles[1] = true
+	assert.Equal(t, uint32(2), r.add(sub, "Press", "Counter"), "handles in use are skipped")
+
+	r.lastHandle = math.MaxUint32
+	assert.Equal(t, uint32(3), r.add(sub, "Press", "Torque"), "handle 0 is skipped")
+}
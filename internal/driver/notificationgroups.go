@@ -0,0 +1,79 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+)
+
+// groupValues groups the values of a device received in one notification into async values,
+// according to the configured NotificationGrouping. The order of the values is kept within
+// a group, and groups are ordered by their first value.
+func (d *Driver) groupValues(deviceName string, values []*sdkModels.CommandValue) []*sdkModels.AsyncValues {
+	switch d.serviceConfig.OPCUAServer.NotificationGrouping {
+	case GroupingDevice:
+		return []*sdkModels.AsyncValues{{DeviceName: deviceName, CommandValues: values}}
+	case GroupingCommand:
+		return d.groupValuesByCommand(deviceName, values)
+	}
+
+	groups := make([]*sdkModels.AsyncValues, len(values))
+	for i, value := range values {
+		groups[i] = &sdkModels.AsyncValues{DeviceName: deviceName, CommandValues: []*sdkModels.CommandValue{value}}
+	}
+	return groups
+}
+
+// groupValuesByCommand groups values by the first readable device command of the profile holding
+// their resource, the command being the source of the event. Values of resources read by no command
+// are grouped together.
+func (d *Driver) groupValuesByCommand(deviceName string, values []*sdkModels.CommandValue) []*sdkModels.AsyncValues {
+	commands := d.resourceCommands(deviceName)
+
+	var groups []*sdkModels.AsyncValues
+	index := make(map[string]*sdkModels.AsyncValues)
+	for _, value := range values {
+		source := commands[value.DeviceResourceName]
+		group, ok := index[source]
+		if !ok {
+			group = &sdkModels.AsyncValues{DeviceName: deviceName, SourceName: source}
+			index[source] = group
+			groups = append(groups, group)
+		}
+		group.CommandValues = append(group.CommandValues, value)
+	}
+	return groups
+}
+
+// resourceCommands returns the first readable device command holding each resource of the profile of a device
+func (d *Driver) resourceCommands(deviceName string) map[string]string {
+	commands := make(map[string]string)
+	if d.sdkService == nil {
+		return commands
+	}
+	device, err := d.sdkService.GetDeviceByName(deviceName)
+	if err != nil {
+		return commands
+	}
+	profile, err := d.sdkService.GetProfileByName(device.ProfileName)
+	if err != nil {
+		return commands
+	}
+
+	for _, command := range profile.DeviceCommands {
+		if command.ReadWrite == common.ReadWrite_W {
+			continue
+		}
+		for _, operation := range command.ResourceOperations {
+			if _, ok := commands[operation.DeviceResource]; !ok {
+				commands[operation.DeviceResource] = command.Name
+			}
+		}
+	}
+	return commands
+}
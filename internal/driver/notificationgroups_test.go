@@ -0,0 +1,68 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"testing"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriver_groupValues(t *testing.T) {
+	sdk := &deviceServiceSDK{
+		devices: map[string]models.Device{"Test": {Name: "Test", ProfileName: "Machine"}},
+		profiles: map[string]models.DeviceProfile{"Machine": {Name: "Machine", DeviceCommands: []models.DeviceCommand{
+			{Name: "SetSpeed", ReadWrite: common.ReadWrite_W, ResourceOperations: []models.ResourceOperation{{DeviceResource: "Speed"}}},
+			{Name: "Drive", ReadWrite: common.ReadWrite_RW, ResourceOperations: []models.ResourceOperation{{DeviceResource: "Speed"}, {DeviceResource: "Torque"}}},
+			{Name: "Status", ReadWrite: common.ReadWrite_R, ResourceOperations: []models.ResourceOperation{{DeviceResource: "Running"}, {DeviceResource: "Speed"}}},
+		}}},
+	}
+	speed := &sdkModels.CommandValue{DeviceResourceName: "Speed"}
+	running := &sdkModels.CommandValue{DeviceResourceName: "Running"}
+	torque := &sdkModels.CommandValue{DeviceResourceName: "Torque"}
+	counter := &sdkModels.CommandValue{DeviceResourceName: "Counter"}
+	values := []*sdkModels.CommandValue{speed, running, torque, counter}
+
+	tests := []struct {
+		name     string
+		grouping string
+		want     []*sdkModels.AsyncValues
+	}{
+		{
+			name: "one event per value by default",
+			want: []*sdkModels.AsyncValues{
+				{DeviceName: "Test", CommandValues: []*sdkModels.CommandValue{speed}},
+				{DeviceName: "Test", CommandValues: []*sdkModels.CommandValue{running}},
+				{DeviceName: "Test", CommandValues: []*sdkModels.CommandValue{torque}},
+				{DeviceName: "Test", CommandValues: []*sdkModels.CommandValue{counter}},
+			},
+		},
+		{
+			name:     "one event per device",
+			grouping: GroupingDevice,
+			want:     []*sdkModels.AsyncValues{{DeviceName: "Test", CommandValues: values}},
+		},
+		{
+			name:     "one event per command",
+			grouping: GroupingCommand,
+			want: []*sdkModels.AsyncValues{
+				{DeviceName: "Test", SourceName: "Drive", CommandValues: []*sdkModels.CommandValue{speed, torque}},
+				{DeviceName: "Test", SourceName: "Status", CommandValues: []*sdkModels.CommandValue{running}},
+				{DeviceName: "Test", CommandValues: []*sdkModels.CommandValue{counter}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Driver{sdkService: sdk, serviceConfig: &ServiceConfig{OPCUAServer: OPCUAServerConfig{NotificationGrouping: tt.grouping}}}
+			assert.Equal(t, tt.want, d.groupValues("Test", values))
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"fmt"
+	"strings"
+
+	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/gopcua/opcua/ua"
+)
+
+const (
+	severityMask      = 0xC0000000
+	severityUncertain = 0x40000000
+	subCodeMask       = 0xFFFF0000
+)
+
+// newDataValueResult creates the reading for a data value returned by the server.
+// The configured quality policy decides whether a value which is not Good is
+// published, dropped or replaced by the last Good value of the resource.
+func (d *Driver) newDataValueResult(deviceName string, req sdkModel.CommandRequest, dataValue *ua.DataValue) (*sdkModel.CommandValue, error) {
+	var result *sdkModel.CommandValue
+	var err error
+
+	switch d.qualityPolicy(dataValue.Status) {
+	case QualityDrop:
+		return nil, fmt.Errorf("status not Good: %v", dataValue.Status)
+	case QualityReplace:
+		result, err = d.lastGoodValue(deviceName, req.DeviceResourceName)
+		if err != nil {
+			return nil, fmt.Errorf("status not Good: %v; %v", dataValue.Status, err)
+		}
+	default:
+		if dataValue.Value == nil {
+			return nil, fmt.Errorf("no value returned with status %v", dataValue.Status)
+		}
+		reading := dataValue.Value.Value()
+		if req.Type == common.ValueTypeObject {
+			reading, err = d.structureReading(deviceName, reading)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if req.Type == common.ValueTypeString || req.Type == common.ValueTypeStringArray {
+			reading = enumText(d.resourceDataType(deviceName, req), reading)
+		}
+		if _, ok := req.Attributes[INDEXRANGE]; ok {
+			reading = indexRangeReading(req.Type, reading)
+		}
+		if props, ok := d.resourceProperties(deviceName, req.DeviceResourceName); ok {
+			reading, err = transformReading(req.Type, props, reading)
+			if err != nil {
+				return nil, fmt.Errorf("fail to transform %v reading, %v", req.DeviceResourceName, err)
+			}
+		}
+		result, err = newResult(req, reading)
+		if err != nil {
+			return nil, err
+		}
+		if isGood(dataValue.Status) {
+			d.storeLastGoodValue(deviceName, result)
+		}
+	}
+
+	result.Origin = readingOrigin(dataValue, d.timestampSource())
+	setQualityTags(result, dataValue.Status)
+	d.setUnitsTag(deviceName, req, result)
+
+	return result, nil
+}
+
+// qualityPolicy returns the configured policy for the severity of the status code
+func (d *Driver) qualityPolicy(status ua.StatusCode) string {
+	if isGood(status) {
+		return QualityPublish
+	}
+
+	var uncertainPolicy, badPolicy string
+	if d.serviceConfig != nil {
+		uncertainPolicy = d.serviceConfig.OPCUAServer.UncertainPolicy
+		badPolicy = d.serviceConfig.OPCUAServer.BadPolicy
+	}
+
+	if isUncertain(status) {
+		if uncertainPolicy == "" {
+			return QualityPublish
+		}
+		return uncertainPolicy
+	}
+	if badPolicy == "" {
+		return QualityDrop
+	}
+	return badPolicy
+}
+
+func (d *Driver) storeLastGoodValue(deviceName string, result *sdkModel.CommandValue) {
+	d.lastGoodMu.Lock()
+	defer d.lastGoodMu.Unlock()
+
+	if d.lastGoodValues == nil {
+		d.lastGoodValues = make(map[string]sdkModel.CommandValue)
+	}
+	value := *result
+	value.Tags = nil
+	d.lastGoodValues[deviceName+"/"+result.DeviceResourceName] = value
+}
+
+func (d *Driver) lastGoodValue(deviceName, resourceName string) (*sdkModel.CommandValue, error) {
+	d.lastGoodMu.Lock()
+	defer d.lastGoodMu.Unlock()
+
+	value, ok := d.lastGoodValues[deviceName+"/"+resourceName]
+	if !ok {
+		return nil, fmt.Errorf("no Good value of %s received yet", resourceName)
+	}
+	value.Tags = make(map[string]string)
+	return &value, nil
+}
+
+// setQualityTags attaches the status code and its symbolic name to the reading
+func setQualityTags(result *sdkModel.CommandValue, status ua.StatusCode) {
+	if result.Tags == nil {
+		result.Tags = make(map[string]string)
+	}
+	result.Tags[StatusCodeTag] = fmt.Sprintf("0x%08X", uint32(status))
+	result.Tags[StatusNameTag] = statusName(status)
+}
+
+// statusName returns the symbolic name of the status code, ignoring its info bits
+func statusName(status ua.StatusCode) string {
+	if desc, ok := ua.StatusCodes[status&subCodeMask]; ok {
+		return strings.TrimPrefix(desc.Name, "Status")
+	}
+	switch {
+	case isGood(status):
+		return "Good"
+	case isUncertain(status):
+		return "Uncertain"
+	default:
+		return "Bad"
+	}
+}
+
+func isGood(status ua.StatusCode) bool {
+	return status&severityMask == 0
+}
+
+func isUncertain(status ua.StatusCode) bool {
+	return status&severityMask == severityUncertain
+}
@@ -0,0 +1,124 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"testing"
+
+	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriver_newDataValueResult(t *testing.T) {
+	req := sdkModel.CommandRequest{DeviceResourceName: "Counter", Type: common.ValueTypeInt32}
+	good := &ua.DataValue{Value: ua.MustVariant(int32(5)), Status: ua.StatusOK}
+	uncertain := &ua.DataValue{Value: ua.MustVariant(int32(6)), Status: ua.StatusUncertainLastUsableValue}
+	bad := &ua.DataValue{Status: ua.StatusBadNodeIDUnknown}
+
+	tests := []struct {
+		name            string
+		uncertainPolicy string
+		badPolicy       string
+		values          []*ua.DataValue
+		want            interface{}
+		wantStatusName  string
+		wantErr         bool
+	}{
+		{
+			name:           "OK - good value",
+			values:         []*ua.DataValue{good},
+			want:           int32(5),
+			wantStatusName: "Good",
+		},
+		{
+			name:           "OK - uncertain value published by default",
+			values:         []*ua.DataValue{uncertain},
+			want:           int32(6),
+			wantStatusName: "UncertainLastUsableValue",
+		},
+		{
+			name:    "NOK - bad value dropped by default",
+			values:  []*ua.DataValue{bad},
+			wantErr: true,
+		},
+		{
+			name:            "NOK - uncertain value dropped",
+			uncertainPolicy: QualityDrop,
+			values:          []*ua.DataValue{uncertain},
+			wantErr:         true,
+		},
+		{
+			name:      "NOK - bad value published without a value",
+			badPolicy: QualityPublish,
+			values:    []*ua.DataValue{bad},
+			wantErr:   true,
+		},
+		{
+			name:      "NOK - bad value replaced without a Good value",
+			badPolicy: QualityReplace,
+			values:    []*ua.DataValue{bad},
+			wantErr:   true,
+		},
+		{
+			name:           "OK - bad value replaced by the last Good value",
+			badPolicy:      QualityReplace,
+			values:         []*ua.DataValue{good, bad},
+			want:           int32(5),
+			wantStatusName: "BadNodeIDUnknown",
+		},
+		{
+			name:            "OK - uncertain value replaced by the last Good value",
+			uncertainPolicy: QualityReplace,
+			values:          []*ua.DataValue{good, uncertain},
+			want:            int32(5),
+			wantStatusName:  "UncertainLastUsableValue",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Driver{serviceConfig: &ServiceConfig{OPCUAServer: OPCUAServerConfig{
+				UncertainPolicy: tt.uncertainPolicy,
+				BadPolicy:       tt.badPolicy,
+			}}}
+
+			var got *sdkModel.CommandValue
+			var err error
+			for _, value := range tt.values {
+				got, err = d.newDataValueResult("Test", req, value)
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Value)
+			assert.Equal(t, tt.wantStatusName, got.Tags[StatusNameTag])
+		})
+	}
+}
+
+func Test_statusName(t *testing.T) {
+	tests := []struct {
+		name   string
+		status ua.StatusCode
+		want   string
+	}{
+		{"Good", ua.StatusOK, "Good"},
+		{"Uncertain", ua.StatusUncertain, "Uncertain"},
+		{"Bad sub code", ua.StatusBadTimeout, "BadTimeout"},
+		{"Info bits ignored", ua.StatusBadTimeout | 0x0400, "BadTimeout"},
+		{"Unknown uncertain sub code", 0x40FF0000, "Uncertain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, statusName(tt.status))
+		})
+	}
+}
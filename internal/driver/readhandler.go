@@ -10,7 +10,6 @@
 package driver
 
 import (
-	"context"
 	"fmt"
 
 	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
@@ -31,21 +30,26 @@ func (d *Driver) HandleReadCommands(deviceName string, protocols map[string]mode
 		return nil, err
 	}
 
-	client, cliErr := d.buildClient(context.Background(), endpoint)
+	settings, err := fetchDeviceSettings(protocols)
+	if err != nil {
+		return nil, err
+	}
+
+	client, cliErr := d.buildClient(deviceName, endpoint, settings)
 	if cliErr != nil {
-		d.Logger.Warnf("Driver.HandleReadCommands: Failed to connect OPCUA client, %s", err)
+		d.Logger.Warnf("Driver.HandleReadCommands: Failed to connect OPCUA client, %s", cliErr)
 		return nil, cliErr
 	}
 
-	return d.processReadCommands(client, reqs)
+	return d.processReadCommands(deviceName, client, settings, reqs)
 }
 
-func (d *Driver) processReadCommands(client *opcua.Client, reqs []sdkModel.CommandRequest) ([]*sdkModel.CommandValue, error) {
+func (d *Driver) processReadCommands(deviceName string, client *opcua.Client, settings deviceSettings, reqs []sdkModel.CommandRequest) ([]*sdkModel.CommandValue, error) {
 	var responses = make([]*sdkModel.CommandValue, len(reqs))
 
 	for i, req := range reqs {
 		// handle every reqs
-		res, err := d.handleReadCommandRequest(client, req)
+		res, err := d.handleReadCommandRequest(deviceName, client, settings, req)
 		if err != nil {
 			d.Logger.Errorf("Driver.HandleReadCommands: Handle read commands failed: %v", err)
 			return responses, err
@@ -56,102 +60,97 @@ func (d *Driver) processReadCommands(client *opcua.Client, reqs []sdkModel.Comma
 	return responses, nil
 }
 
-func (d *Driver) handleReadCommandRequest(deviceClient *opcua.Client, req sdkModel.CommandRequest) (*sdkModel.CommandValue, error) {
+func (d *Driver) handleReadCommandRequest(deviceName string, deviceClient *opcua.Client, settings deviceSettings, req sdkModel.CommandRequest) (*sdkModel.CommandValue, error) {
 	var result *sdkModel.CommandValue
 	var err error
 
 	_, isMethod := req.Attributes[METHOD]
+	_, isHistory := req.Attributes[HISTORY]
 
 	if isMethod {
-		result, err = makeMethodCall(deviceClient, req)
+		result, err = d.makeMethodCall(deviceName, deviceClient, settings, req)
 		d.Logger.Infof("Method command finished: %v", result)
+	} else if isHistory {
+		result, err = d.makeHistoryRead(deviceName, deviceClient, settings, req)
+		d.Logger.Infof("History read command finished: %v", result)
 	} else {
-		result, err = makeReadRequest(deviceClient, req)
+		result, err = d.makeReadRequest(deviceName, deviceClient, settings, req)
 		d.Logger.Infof("Read command finished: %v", result)
 	}
 
 	return result, err
 }
 
-func makeReadRequest(deviceClient *opcua.Client, req sdkModel.CommandRequest) (*sdkModel.CommandValue, error) {
-	nodeID, err := getNodeID(req.Attributes, NODE)
+func (d *Driver) makeReadRequest(deviceName string, deviceClient *opcua.Client, settings deviceSettings, req sdkModel.CommandRequest) (*sdkModel.CommandValue, error) {
+	id, err := d.resourceNodeID(deviceName, deviceClient, req.Attributes, NODE)
+	if err != nil {
+		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
+	}
+
+	attributeID, err := getAttributeID(req.Attributes)
 	if err != nil {
 		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
 	}
 
-	id, err := ua.ParseNodeID(nodeID)
+	indexRange, err := getIndexRange(req.Attributes)
 	if err != nil {
-		return nil, fmt.Errorf("Driver.handleReadCommands: Invalid node id=%s; %v", nodeID, err)
+		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
+	}
+
+	if err := d.prepareDataType(deviceName, deviceClient, req.Type, attributeID, id); err != nil {
+		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
+	}
+	d.prepareUnits(deviceName, deviceClient, req.DeviceResourceName, req.Attributes)
+
+	maxAge, err := d.maxAge(settings, req.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
 	}
 
 	request := &ua.ReadRequest{
-		MaxAge: 2000,
+		MaxAge: maxAge,
 		NodesToRead: []*ua.ReadValueID{
-			{NodeID: id},
+			{NodeID: d.registeredNodeID(deviceName, deviceClient, settings, req.DeviceResourceName, id), AttributeID: attributeID, IndexRange: indexRange},
 		},
 		TimestampsToReturn: ua.TimestampsToReturnBoth,
 	}
 
-	ctx := context.Background()
+	ctx, cancel := d.requestContext(settings)
+	defer cancel()
 	resp, err := deviceClient.Read(ctx, request)
 	if err != nil {
 		return nil, fmt.Errorf("Driver.handleReadCommands: Read failed: %s", err)
 	}
-	if resp.Results[0].Status != ua.StatusOK {
-		return nil, fmt.Errorf("Driver.handleReadCommands: Status not OK: %v", resp.Results[0].Status)
-	}
+	d.checkNodeStatus(deviceClient, resp.Results[0].Status)
 
 	// make new result
-	reading := resp.Results[0].Value.Value()
-	return newResult(req, reading)
-}
-
-func makeMethodCall(deviceClient *opcua.Client, req sdkModel.CommandRequest) (*sdkModel.CommandValue, error) {
-	var inputs []*ua.Variant
-
-	objectID, err := getNodeID(req.Attributes, OBJECT)
+	result, err := d.newDataValueResult(deviceName, req, resp.Results[0])
 	if err != nil {
 		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
 	}
-	oid, err := ua.ParseNodeID(objectID)
+
+	return result, nil
+}
+
+func (d *Driver) makeMethodCall(deviceName string, deviceClient *opcua.Client, settings deviceSettings, req sdkModel.CommandRequest) (*sdkModel.CommandValue, error) {
+	oid, mid, inputMap, err := d.methodAttributes(deviceName, deviceClient, req.Attributes)
 	if err != nil {
 		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
 	}
 
-	methodID, err := getNodeID(req.Attributes, METHOD)
+	outputArgs, err := d.methodOutputs(deviceName, deviceClient, mid, req.Type)
 	if err != nil {
 		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
 	}
-	mid, err := ua.ParseNodeID(methodID)
+
+	resp, err := d.callMethod(deviceName, deviceClient, settings, oid, mid, inputMap, queryParameters(req.Attributes))
 	if err != nil {
 		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
 	}
 
-	inputMap, ok := req.Attributes[INPUTMAP]
-	if ok {
-		imElements := inputMap.([]interface{})
-		if len(imElements) > 0 {
-			inputs = make([]*ua.Variant, len(imElements))
-			for i := 0; i < len(imElements); i++ {
-				inputs[i] = ua.MustVariant(imElements[i].(string))
-			}
-		}
-	}
-
-	request := &ua.CallMethodRequest{
-		ObjectID:       oid,
-		MethodID:       mid,
-		InputArguments: inputs,
-	}
-
-	ctx := context.Background()
-	resp, err := deviceClient.Call(ctx, request)
+	result, err := d.methodResult(deviceName, req, resp, outputArgs)
 	if err != nil {
-		return nil, fmt.Errorf("Driver.handleReadCommands: Method call failed: %s", err)
-	}
-	if resp.StatusCode != ua.StatusOK {
-		return nil, fmt.Errorf("Driver.handleReadCommands: Method status not OK: %v", resp.StatusCode)
+		return nil, fmt.Errorf("Driver.handleReadCommands: %v", err)
 	}
-
-	return newResult(req, resp.OutputArguments[0].Value())
+	return result, nil
 }
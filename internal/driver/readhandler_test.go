@@ -171,7 +171,7 @@ import (
 //		t.Run(tt.name, func(t *testing.T) {
 //			d := &Driver{
 //				Logger:    &logger.MockLogger{},
-//				clientMap: map[string]*opcua.Client{},
+//				clientMap: map[clientKey]*opcua.Client{},
 //			}
 //			got, err := d.HandleReadCommands(tt.args.deviceName, tt.args.protocols, tt.args.reqs)
 //			if (err != nil) != tt.wantErr {
@@ -195,7 +195,7 @@ func Benchmark_HandleReadCommands_ReuseClient(b *testing.B) {
 
 	d := &Driver{
 		Logger:    &logger.MockLogger{},
-		clientMap: map[string]*opcua.Client{},
+		clientMap: map[clientKey]*opcua.Client{},
 	}
 	deviceName := "Test"
 	protocols := map[string]models.ProtocolProperties{
@@ -222,7 +222,7 @@ func Benchmark_HandleReadCommands_WithoutReuseClient(b *testing.B) {
 
 	d := &Driver{
 		Logger:    &logger.MockLogger{},
-		clientMap: map[string]*opcua.Client{},
+		clientMap: map[clientKey]*opcua.Client{},
 	}
 	deviceName := "Test"
 	protocols := map[string]models.ProtocolProperties{
@@ -254,6 +254,11 @@ func handleReadCommandsWithoutReuseClient(
 		return nil, err
 	}
 
+	settings, err := fetchDeviceSettings(protocols)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx := context.Background()
 	client, _ := opcua.NewClient(endpoint, opcua.SecurityMode(ua.MessageSecurityModeNone))
 	if err := client.Connect(ctx); err != nil {
@@ -262,5 +267,5 @@ func handleReadCommandsWithoutReuseClient(
 	}
 	defer client.Close(ctx)
 
-	return d.processReadCommands(client, reqs)
+	return d.processReadCommands(deviceName, client, settings, reqs)
 }
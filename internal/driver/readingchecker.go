@@ -8,12 +8,15 @@
 package driver
 
 import (
+	"encoding/base64"
 	"fmt"
 	"math"
+	"reflect"
 	"time"
 
 	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/gopcua/opcua/ua"
 	"github.com/spf13/cast"
 )
 
@@ -21,6 +24,15 @@ func newResult(req sdkModel.CommandRequest, reading interface{}) (*sdkModel.Comm
 	var result = &sdkModel.CommandValue{}
 	var err error
 	castError := "fail to parse %v reading, %v"
+	locale := readingLocale(reading)
+	if isBase64Encoded(req.Attributes) {
+		reading = base64Reading(reading)
+	}
+	reading = builtinValue(req.Type, reading)
+
+	if _, ok := arrayElementTypes[req.Type]; ok {
+		return newArrayResult(req, reading)
+	}
 
 	if !checkValueInRange(req.Type, reading) {
 		err = fmt.Errorf("parse reading fail. Reading %v is out of the value type(%v)'s range", reading, req.Type)
@@ -90,6 +102,13 @@ func newResult(req sdkModel.CommandRequest, reading interface{}) (*sdkModel.Comm
 		if err != nil {
 			return nil, fmt.Errorf(castError, req.DeviceResourceName, err)
 		}
+	case common.ValueTypeBinary:
+		val, err = binaryValue(reading)
+		if err != nil {
+			return nil, fmt.Errorf(castError, req.DeviceResourceName, err)
+		}
+	case common.ValueTypeObject:
+		val = objectValue(reading)
 	default:
 		err = fmt.Errorf("return result fail, none supported value type: %v", req.Type)
 		return nil, err
@@ -100,15 +119,258 @@ func newResult(req sdkModel.CommandRequest, reading interface{}) (*sdkModel.Comm
 		return nil, err
 	}
 	result.Origin = time.Now().UnixNano()
+	if locale != "" {
+		result.Tags = map[string]string{LocaleTag: locale}
+	}
 
 	return result, err
 }
 
+// arrayElementTypes maps the supported array value types to the type of their elements
+var arrayElementTypes = map[string]string{
+	common.ValueTypeBoolArray:    common.ValueTypeBool,
+	common.ValueTypeStringArray:  common.ValueTypeString,
+	common.ValueTypeUint8Array:   common.ValueTypeUint8,
+	common.ValueTypeUint16Array:  common.ValueTypeUint16,
+	common.ValueTypeUint32Array:  common.ValueTypeUint32,
+	common.ValueTypeUint64Array:  common.ValueTypeUint64,
+	common.ValueTypeInt8Array:    common.ValueTypeInt8,
+	common.ValueTypeInt16Array:   common.ValueTypeInt16,
+	common.ValueTypeInt32Array:   common.ValueTypeInt32,
+	common.ValueTypeInt64Array:   common.ValueTypeInt64,
+	common.ValueTypeFloat32Array: common.ValueTypeFloat32,
+	common.ValueTypeFloat64Array: common.ValueTypeFloat64,
+}
+
+func newArrayResult(req sdkModel.CommandRequest, reading interface{}) (*sdkModel.CommandValue, error) {
+	var val interface{}
+	var err error
+
+	elementType := arrayElementTypes[req.Type]
+	switch req.Type {
+	case common.ValueTypeBoolArray:
+		val, err = castArray(elementType, reading, cast.ToBoolE)
+	case common.ValueTypeStringArray:
+		val, err = castArray(elementType, reading, cast.ToStringE)
+	case common.ValueTypeUint8Array:
+		val, err = castArray(elementType, reading, cast.ToUint8E)
+	case common.ValueTypeUint16Array:
+		val, err = castArray(elementType, reading, cast.ToUint16E)
+	case common.ValueTypeUint32Array:
+		val, err = castArray(elementType, reading, cast.ToUint32E)
+	case common.ValueTypeUint64Array:
+		val, err = castArray(elementType, reading, cast.ToUint64E)
+	case common.ValueTypeInt8Array:
+		val, err = castArray(elementType, reading, cast.ToInt8E)
+	case common.ValueTypeInt16Array:
+		val, err = castArray(elementType, reading, cast.ToInt16E)
+	case common.ValueTypeInt32Array:
+		val, err = castArray(elementType, reading, cast.ToInt32E)
+	case common.ValueTypeInt64Array:
+		val, err = castArray(elementType, reading, cast.ToInt64E)
+	case common.ValueTypeFloat32Array:
+		val, err = castArray(elementType, reading, cast.ToFloat32E)
+	case common.ValueTypeFloat64Array:
+		val, err = castArray(elementType, reading, cast.ToFloat64E)
+	default:
+		err = fmt.Errorf("none supported array value type: %v", req.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse %v reading, %v", req.DeviceResourceName, err)
+	}
+
+	result, err := sdkModel.NewCommandValue(req.DeviceResourceName, req.Type, val)
+	if err != nil {
+		return nil, err
+	}
+	result.Origin = time.Now().UnixNano()
+
+	return result, nil
+}
+
+// castArray casts every element of an array reading, flattening multi-dimensional arrays
+func castArray[T any](elementType string, reading interface{}, castE func(interface{}) (T, error)) ([]T, error) {
+	elements, err := flattenArray(reflect.ValueOf(reading))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]T, len(elements))
+	for i, element := range elements {
+		element = builtinValue(elementType, element)
+		if !checkValueInRange(elementType, element) {
+			return nil, fmt.Errorf("element %d %v is out of the value type(%v)'s range", i, element, elementType)
+		}
+		values[i], err = castE(element)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %v", i, err)
+		}
+	}
+
+	return values, nil
+}
+
+func flattenArray(array reflect.Value) ([]interface{}, error) {
+	if array.Kind() != reflect.Slice && array.Kind() != reflect.Array {
+		return nil, fmt.Errorf("reading %v is not an array", array)
+	}
+
+	elements := make([]interface{}, 0, array.Len())
+	for i := 0; i < array.Len(); i++ {
+		element := array.Index(i)
+		if element.Kind() == reflect.Slice || element.Kind() == reflect.Array {
+			inner, err := flattenArray(element)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, inner...)
+			continue
+		}
+		elements = append(elements, element.Interface())
+	}
+
+	return elements, nil
+}
+
+// indexRangeReading unwraps the single element returned for an index range
+// when the resource has a scalar value type
+func indexRangeReading(valueType string, reading interface{}) interface{} {
+	if _, ok := arrayElementTypes[valueType]; ok {
+		return reading
+	}
+	// a range of a ByteString is a ByteString itself
+	if _, ok := reading.([]byte); ok {
+		return reading
+	}
+
+	rv := reflect.ValueOf(reading)
+	if rv.Kind() == reflect.Slice && rv.Len() == 1 {
+		return rv.Index(0).Interface()
+	}
+	return reading
+}
+
+// builtinValue converts the OPC UA built-in types into plain values which can
+// be cast to the value type of the resource. DateTime values are read as RFC3339
+// strings or as nanoseconds since the Unix epoch, and StatusCode values as their
+// symbolic name or their numeric code.
+func builtinValue(valueType string, reading interface{}) interface{} {
+	switch v := reading.(type) {
+	case *ua.LocalizedText:
+		return v.Text
+	case *ua.QualifiedName:
+		return qualifiedNameString(v)
+	case *ua.NodeID:
+		return v.String()
+	case *ua.ExpandedNodeID:
+		return v.String()
+	case *ua.GUID:
+		return v.String()
+	case ua.XMLElement:
+		return string(v)
+	case ua.StatusCode:
+		if valueType == common.ValueTypeString {
+			return statusName(v)
+		}
+		return uint32(v)
+	case time.Time:
+		switch valueType {
+		case common.ValueTypeString:
+			return v.UTC().Format(time.RFC3339Nano)
+		case common.ValueTypeInt64, common.ValueTypeUint64:
+			return v.UnixNano()
+		}
+	}
+	return reading
+}
+
+// isBase64Encoded reports whether the ByteString values of a resource are read and written
+// as base64 strings, as selected by the encoding attribute
+func isBase64Encoded(attrs map[string]interface{}) bool {
+	encoding, ok := attrs[ENCODING]
+	return ok && fmt.Sprintf("%v", encoding) == Base64Encoding
+}
+
+// checkEncoding checks the encoding attribute of a resource
+func checkEncoding(attrs map[string]interface{}) error {
+	if encoding, ok := attrs[ENCODING]; ok && fmt.Sprintf("%v", encoding) != Base64Encoding {
+		return fmt.Errorf("attribute %s has unsupported value %v, expected %s", ENCODING, encoding, Base64Encoding)
+	}
+	return nil
+}
+
+// base64Reading encodes the ByteString values of a reading, single or in an array, as base64 strings
+func base64Reading(reading interface{}) interface{} {
+	switch v := reading.(type) {
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case [][]byte:
+		encoded := make([]string, len(v))
+		for i, b := range v {
+			encoded[i] = base64.StdEncoding.EncodeToString(b)
+		}
+		return encoded
+	}
+	return reading
+}
+
+// readingLocale returns the locale of a LocalizedText reading
+func readingLocale(reading interface{}) string {
+	if text, ok := reading.(*ua.LocalizedText); ok {
+		return text.Locale
+	}
+	return ""
+}
+
+// qualifiedNameString formats a QualifiedName as <namespace index>:<name>,
+// omitting the index of namespace 0
+func qualifiedNameString(name *ua.QualifiedName) string {
+	if name.NamespaceIndex == 0 {
+		return name.Name
+	}
+	return fmt.Sprintf("%d:%s", name.NamespaceIndex, name.Name)
+}
+
+func binaryValue(reading interface{}) ([]byte, error) {
+	switch v := reading.(type) {
+	case []byte:
+		return v, nil
+	case ua.ByteArray:
+		return []byte(v), nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("unable to cast %#v of type %T to []byte", reading, reading)
+}
+
+// readingOrigin returns the origin of a reading in nanoseconds, taken from the
+// timestamp of the data value selected by source. When the selected timestamp
+// was not supplied by the server, the next available one is used, and the
+// local clock is the last resort.
+func readingOrigin(dataValue *ua.DataValue, source string) int64 {
+	if dataValue == nil || source == TimestampLocal {
+		return time.Now().UnixNano()
+	}
+
+	timestamps := []time.Time{dataValue.SourceTimestamp, dataValue.ServerTimestamp}
+	if source == TimestampServer {
+		timestamps = []time.Time{dataValue.ServerTimestamp, dataValue.SourceTimestamp}
+	}
+	for _, ts := range timestamps {
+		if !ts.IsZero() {
+			return ts.UnixNano()
+		}
+	}
+
+	return time.Now().UnixNano()
+}
+
 // checkValueInRange checks value range is valid
 func checkValueInRange(valueType string, reading interface{}) bool {
 	isValid := false
 
-	if valueType == common.ValueTypeString || valueType == common.ValueTypeBool {
+	if valueType == common.ValueTypeString || valueType == common.ValueTypeBool ||
+		valueType == common.ValueTypeBinary || valueType == common.ValueTypeObject {
 		return true
 	}
 
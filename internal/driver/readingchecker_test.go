@@ -11,9 +11,11 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/gopcua/opcua/ua"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -586,3 +588,179 @@ func TestNewResult_numberToString(t *testing.T) {
 		t.Errorf("Convert new result(%v) failed, error: %v", val, err)
 	}
 }
+
+func TestNewResult_arrays(t *testing.T) {
+	tests := []struct {
+		name      string
+		valueType string
+		reading   interface{}
+		expected  interface{}
+		wantErr   bool
+	}{
+		{"BoolArray", common.ValueTypeBoolArray, []bool{true, false}, []bool{true, false}, false},
+		{"StringArray", common.ValueTypeStringArray, []string{"a", "b"}, []string{"a", "b"}, false},
+		{"Uint8Array from ByteArray", common.ValueTypeUint8Array, ua.ByteArray{1, 2}, []uint8{1, 2}, false},
+		{"Int32Array", common.ValueTypeInt32Array, []int32{-1, 2}, []int32{-1, 2}, false},
+		{"Int64Array from Int32 array", common.ValueTypeInt64Array, []int32{-1, 2}, []int64{-1, 2}, false},
+		{"Float64Array", common.ValueTypeFloat64Array, []float64{1.5, 2.5}, []float64{1.5, 2.5}, false},
+		{"Float32Array multi-dimensional", common.ValueTypeFloat32Array, [][]float32{{1, 2}, {3, 4}}, []float32{1, 2, 3, 4}, false},
+		{"Empty Uint16Array", common.ValueTypeUint16Array, []uint16{}, []uint16{}, false},
+		{"Int8Array out of range", common.ValueTypeInt8Array, []int16{1, 256}, nil, true},
+		{"Scalar reading", common.ValueTypeInt32Array, int32(1), nil, true},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := models.CommandRequest{
+				DeviceResourceName: "waveform",
+				Type:               testCase.valueType,
+			}
+			cmdVal, err := newResult(req, testCase.reading)
+			if testCase.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.valueType, cmdVal.Type)
+			assert.Equal(t, testCase.expected, cmdVal.Value)
+		})
+	}
+}
+
+func TestIndexRangeReading(t *testing.T) {
+	tests := []struct {
+		name      string
+		valueType string
+		reading   interface{}
+		expected  interface{}
+	}{
+		{"Single element of scalar resource", common.ValueTypeInt32, []int32{5}, int32(5)},
+		{"Single byte of scalar resource", common.ValueTypeUint8, ua.ByteArray{5}, uint8(5)},
+		{"Single element of array resource", common.ValueTypeInt32Array, []int32{5}, []int32{5}},
+		{"Sub range of scalar resource", common.ValueTypeInt32, []int32{5, 6}, []int32{5, 6}},
+		{"Range of byte string", common.ValueTypeString, []byte("a"), []byte("a")},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, indexRangeReading(testCase.valueType, testCase.reading))
+		})
+	}
+}
+
+func TestNewResult_attributeValues(t *testing.T) {
+	req := models.CommandRequest{
+		DeviceResourceName: "attribute",
+		Type:               common.ValueTypeString,
+	}
+
+	tests := []struct {
+		name     string
+		reading  interface{}
+		expected string
+	}{
+		{"LocalizedText", ua.NewLocalizedTextWithLocale("Speed", "en"), "Speed"},
+		{"QualifiedName", &ua.QualifiedName{NamespaceIndex: 2, Name: "Speed"}, "2:Speed"},
+		{"QualifiedName namespace 0", &ua.QualifiedName{Name: "Speed"}, "Speed"},
+		{"NodeID", ua.NewNumericNodeID(0, 11), "i=11"},
+		{"ExpandedNodeID", ua.NewNumericExpandedNodeID(2, 11), "ns=2;i=11"},
+		{"Guid", ua.NewGUID("72962B91-FA75-4AE6-8D28-B404DC7DAF63"), "72962B91-FA75-4AE6-8D28-B404DC7DAF63"},
+		{"DateTime", time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC), "2024-01-02T03:04:05.000000006Z"},
+		{"ByteString", []byte{0x61, 0x62}, "ab"},
+		{"StatusCode", ua.StatusBadTimeout, "BadTimeout"},
+		{"XmlElement", ua.XMLElement("<a/>"), "<a/>"},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			cmdVal, err := newResult(req, testCase.reading)
+			require.NoError(t, err)
+			result, err := cmdVal.StringValue()
+			require.NoError(t, err)
+
+			assert.Equal(t, testCase.expected, result)
+		})
+	}
+}
+
+func TestNewResult_builtinTypes(t *testing.T) {
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	tests := []struct {
+		name      string
+		valueType string
+		reading   interface{}
+		expected  interface{}
+	}{
+		{"DateTime as epoch", common.ValueTypeInt64, timestamp, timestamp.UnixNano()},
+		{"StatusCode as code", common.ValueTypeUint32, ua.StatusBadTimeout, uint32(0x800A0000)},
+		{"ByteString as binary", common.ValueTypeBinary, []byte{0x01, 0x02}, []byte{0x01, 0x02}},
+		{"Byte array as binary", common.ValueTypeBinary, ua.ByteArray{0x01}, []byte{0x01}},
+		{"Guid array", common.ValueTypeStringArray, []*ua.GUID{ua.NewGUID("72962B91-FA75-4AE6-8D28-B404DC7DAF63")}, []string{"72962B91-FA75-4AE6-8D28-B404DC7DAF63"}},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := models.CommandRequest{DeviceResourceName: "builtin", Type: testCase.valueType}
+			cmdVal, err := newResult(req, testCase.reading)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, cmdVal.Value)
+		})
+	}
+}
+
+func TestNewResult_base64Encoding(t *testing.T) {
+	attrs := map[string]interface{}{ENCODING: Base64Encoding}
+
+	cmdVal, err := newResult(models.CommandRequest{DeviceResourceName: "data", Type: common.ValueTypeString, Attributes: attrs}, []byte{0x01, 0x02, 0xff})
+	require.NoError(t, err)
+	assert.Equal(t, "AQL/", cmdVal.Value)
+
+	cmdVal, err = newResult(models.CommandRequest{DeviceResourceName: "data", Type: common.ValueTypeStringArray, Attributes: attrs}, [][]byte{{0x01}, {0xff}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"AQ==", "/w=="}, cmdVal.Value)
+}
+
+func TestNewResult_localeTag(t *testing.T) {
+	req := models.CommandRequest{DeviceResourceName: "text", Type: common.ValueTypeString}
+
+	cmdVal, err := newResult(req, ua.NewLocalizedTextWithLocale("Vitesse", "fr"))
+	require.NoError(t, err)
+	assert.Equal(t, "Vitesse", cmdVal.Value)
+	assert.Equal(t, "fr", cmdVal.Tags[LocaleTag])
+
+	cmdVal, err = newResult(req, ua.NewLocalizedText("Speed"))
+	require.NoError(t, err)
+	assert.NotContains(t, cmdVal.Tags, LocaleTag)
+}
+
+func TestReadingOrigin(t *testing.T) {
+	sourceTime := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	serverTime := sourceTime.Add(time.Second)
+
+	tests := []struct {
+		name      string
+		dataValue *ua.DataValue
+		source    string
+		expected  int64
+	}{
+		{"Default uses source timestamp", &ua.DataValue{SourceTimestamp: sourceTime, ServerTimestamp: serverTime}, "", sourceTime.UnixNano()},
+		{"Source timestamp", &ua.DataValue{SourceTimestamp: sourceTime, ServerTimestamp: serverTime}, TimestampSource, sourceTime.UnixNano()},
+		{"Server timestamp", &ua.DataValue{SourceTimestamp: sourceTime, ServerTimestamp: serverTime}, TimestampServer, serverTime.UnixNano()},
+		{"Missing source falls back to server", &ua.DataValue{ServerTimestamp: serverTime}, TimestampSource, serverTime.UnixNano()},
+		{"Missing server falls back to source", &ua.DataValue{SourceTimestamp: sourceTime}, TimestampServer, sourceTime.UnixNano()},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, readingOrigin(testCase.dataValue, testCase.source))
+		})
+	}
+
+	t.Run("Local time", func(t *testing.T) {
+		before := time.Now().UnixNano()
+		origin := readingOrigin(&ua.DataValue{SourceTimestamp: sourceTime}, TimestampLocal)
+		assert.GreaterOrEqual(t, origin, before)
+	})
+
+	t.Run("No timestamps fall back to local time", func(t *testing.T) {
+		before := time.Now().UnixNano()
+		origin := readingOrigin(&ua.DataValue{}, TimestampSource)
+		assert.GreaterOrEqual(t, origin, before)
+	})
+}
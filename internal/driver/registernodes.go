@@ -0,0 +1,229 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+)
+
+// nodeRegistrations holds the nodes registered per client, keyed by device and node id, and
+// whether the resources of the devices are polled
+type nodeRegistrations struct {
+	mu    sync.Mutex
+	nodes map[*opcua.Client]map[string]*ua.NodeID
+	// clients whose server does not support registering nodes
+	unsupported map[*opcua.Client]bool
+	polled      map[string]bool
+}
+
+func (r *nodeRegistrations) get(client *opcua.Client, key string) (*ua.NodeID, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.unsupported[client] {
+		return nil, false
+	}
+	registered, ok := r.nodes[client][key]
+	return registered, ok
+}
+
+func (r *nodeRegistrations) store(client *opcua.Client, key string, registered *ua.NodeID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.nodes == nil {
+		r.nodes = make(map[*opcua.Client]map[string]*ua.NodeID)
+	}
+	if r.nodes[client] == nil {
+		r.nodes[client] = make(map[string]*ua.NodeID)
+	}
+	r.nodes[client][key] = registered
+}
+
+func (r *nodeRegistrations) setUnsupported(client *opcua.Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.unsupported == nil {
+		r.unsupported = make(map[*opcua.Client]bool)
+	}
+	r.unsupported[client] = true
+}
+
+func (r *nodeRegistrations) isUnsupported(client *opcua.Client) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.unsupported[client]
+}
+
+// isPolled returns the cached polling of a resource
+func (r *nodeRegistrations) isPolled(key string) (polled bool, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	polled, ok = r.polled[key]
+	return polled, ok
+}
+
+func (r *nodeRegistrations) storePolled(key string, polled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.polled == nil {
+		r.polled = make(map[string]bool)
+	}
+	r.polled[key] = polled
+}
+
+// takeClient drops the nodes registered by a client and returns them
+func (r *nodeRegistrations) takeClient(client *opcua.Client) []*ua.NodeID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.unsupported, client)
+	var nodes []*ua.NodeID
+	for _, registered := range r.nodes[client] {
+		nodes = append(nodes, registered)
+	}
+	delete(r.nodes, client)
+	return nodes
+}
+
+// takeDevice drops the nodes registered for a device and its polled resources, and returns
+// the nodes per client
+func (r *nodeRegistrations) takeDevice(deviceName string) map[*opcua.Client][]*ua.NodeID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prefix := deviceName + "/"
+	for key := range r.polled {
+		if strings.HasPrefix(key, prefix) {
+			delete(r.polled, key)
+		}
+	}
+	taken := make(map[*opcua.Client][]*ua.NodeID)
+	for client, nodes := range r.nodes {
+		for key, registered := range nodes {
+			if strings.HasPrefix(key, prefix) {
+				taken[client] = append(taken[client], registered)
+				delete(nodes, key)
+			}
+		}
+	}
+	return taken
+}
+
+// registerNodesInterval returns the longest AutoEvent interval whose resources are
+// registered on the server, or zero when nodes are not registered
+func (d *Driver) registerNodesInterval() time.Duration {
+	if d.serviceConfig == nil || d.serviceConfig.OPCUAServer.RegisterNodesInterval == "" {
+		return 0
+	}
+	interval, err := time.ParseDuration(d.serviceConfig.OPCUAServer.RegisterNodesInterval)
+	if err != nil {
+		return 0
+	}
+	return interval
+}
+
+// isPolled reports whether a resource is read by an AutoEvent of the device at least
+// as often as the RegisterNodesInterval. The result is cached until the device is updated.
+func (d *Driver) isPolled(deviceName string, resourceName string) bool {
+	interval := d.registerNodesInterval()
+	if interval == 0 || d.sdkService == nil {
+		return false
+	}
+	key := deviceName + "/" + resourceName
+	if polled, ok := d.registeredNodes.isPolled(key); ok {
+		return polled
+	}
+	device, err := d.sdkService.GetDeviceByName(deviceName)
+	if err != nil {
+		return false
+	}
+	polled := d.autoEventPolls(deviceName, device, interval, resourceName)
+	d.registeredNodes.storePolled(key, polled)
+	return polled
+}
+
+// autoEventPolls reports whether a resource is read by an AutoEvent of a device at least as often as an interval
+func (d *Driver) autoEventPolls(deviceName string, device models.Device, interval time.Duration, resourceName string) bool {
+	for _, event := range device.AutoEvents {
+		every, err := time.ParseDuration(event.Interval)
+		if err != nil || every > interval {
+			continue
+		}
+		if event.SourceName == resourceName {
+			return true
+		}
+		// the source of an AutoEvent may also be a device command
+		if command, ok := d.sdkService.DeviceCommand(deviceName, event.SourceName); ok && hasResourceOperation(command, resourceName) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasResourceOperation(command models.DeviceCommand, resourceName string) bool {
+	for _, operation := range command.ResourceOperations {
+		if operation.DeviceResource == resourceName {
+			return true
+		}
+	}
+	return false
+}
+
+// registeredNodeID returns the node id to read or write a resource with. The nodes of polled
+// resources are registered once per client, so that the server can optimize their access.
+// Registrations are dropped when the client reconnects, and made again on the next access.
+// Failed registrations are tried again on the next access, unless the server does not support them.
+func (d *Driver) registeredNodeID(deviceName string, client *opcua.Client, settings deviceSettings, resourceName string, nodeID *ua.NodeID) *ua.NodeID {
+	if !d.isPolled(deviceName, resourceName) || d.registeredNodes.isUnsupported(client) {
+		return nodeID
+	}
+	key := deviceName + "/" + nodeID.String()
+	if registered, ok := d.registeredNodes.get(client, key); ok {
+		return registered
+	}
+
+	ctx, cancel := d.requestContext(settings)
+	defer cancel()
+	resp, err := client.RegisterNodes(ctx, &ua.RegisterNodesRequest{NodesToRegister: []*ua.NodeID{nodeID}})
+	if err == nil && len(resp.RegisteredNodeIDs) != 1 {
+		err = ua.StatusBadUnexpectedError
+	}
+	if err != nil {
+		// the node is then accessed by its own id
+		d.Logger.Debugf("Unable to register node %s: %v", nodeID, err)
+		if err == ua.StatusBadServiceUnsupported {
+			d.registeredNodes.setUnsupported(client)
+		}
+		return nodeID
+	}
+	d.registeredNodes.store(client, key, resp.RegisteredNodeIDs[0])
+	return resp.RegisteredNodeIDs[0]
+}
+
+// unregisterNodes releases nodes registered by a client. The nodes registered in a session
+// which is no longer active are released by the server.
+func (d *Driver) unregisterNodes(client *opcua.Client, nodes []*ua.NodeID) {
+	if len(nodes) == 0 || client.State() != opcua.Connected {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), clientCloseTimeout)
+	defer cancel()
+	if _, err := client.UnregisterNodes(ctx, &ua.UnregisterNodesRequest{NodesToUnregister: nodes}); err != nil {
+		d.Logger.Debugf("Unable to unregister %d nodes: %v", len(nodes), err)
+	}
+}
+
+// forgetRegisteredNodes releases the nodes registered for a device, whose AutoEvents may have changed
+func (d *Driver) forgetRegisteredNodes(deviceName string) {
+	for client, nodes := range d.registeredNodes.takeDevice(deviceName) {
+		d.unregisterNodes(client, nodes)
+	}
+}
@@ -0,0 +1,139 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/edgexfoundry/device-sdk-go/v4/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deviceServiceSDK serves the devices, profiles, commands and resources of the tests, other methods are not implemented
+type deviceServiceSDK struct {
+	interfaces.DeviceServiceSDK
+	devices   map[string]models.Device
+	profiles  map[string]models.DeviceProfile
+	commands  map[string]models.DeviceCommand
+	resources map[string]models.DeviceResource
+}
+
+func (s *deviceServiceSDK) GetDeviceByName(name string) (models.Device, error) {
+	device, ok := s.devices[name]
+	if !ok {
+		return models.Device{}, fmt.Errorf("device %s not found", name)
+	}
+	return device, nil
+}
+
+func (s *deviceServiceSDK) GetProfileByName(name string) (models.DeviceProfile, error) {
+	profile, ok := s.profiles[name]
+	if !ok {
+		return models.DeviceProfile{}, fmt.Errorf("profile %s not found", name)
+	}
+	return profile, nil
+}
+
+func (s *deviceServiceSDK) DeviceCommand(deviceName string, commandName string) (models.DeviceCommand, bool) {
+	command, ok := s.commands[commandName]
+	return command, ok
+}
+
+func (s *deviceServiceSDK) DeviceResource(deviceName string, deviceResource string) (models.DeviceResource, bool) {
+	resource, ok := s.resources[deviceResource]
+	return resource, ok
+}
+
+func TestDriver_isPolled(t *testing.T) {
+	sdk := &deviceServiceSDK{
+		devices: map[string]models.Device{
+			"Test": {Name: "Test", AutoEvents: []models.AutoEvent{
+				{Interval: "500ms", SourceName: "Speed"},
+				{Interval: "1s", SourceName: "Status"},
+				{Interval: "1m", SourceName: "Temperature"},
+				{Interval: "soon", SourceName: "Pressure"},
+			}},
+		},
+		commands: map[string]models.DeviceCommand{
+			"Status": {Name: "Status", ResourceOperations: []models.ResourceOperation{{DeviceResource: "Running"}, {DeviceResource: "Alarm"}}},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		interval     string
+		deviceName   string
+		resourceName string
+		want         bool
+	}{
+		{"resource polled fast", "1s", "Test", "Speed", true},
+		{"resource of a command polled fast", "1s", "Test", "Alarm", true},
+		{"resource polled slowly", "1s", "Test", "Temperature", false},
+		{"invalid AutoEvent interval", "1s", "Test", "Pressure", false},
+		{"resource not polled", "1s", "Test", "Counter", false},
+		{"unknown device", "1s", "Other", "Speed", false},
+		{"registration disabled", "", "Test", "Speed", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Driver{
+				sdkService:    sdk,
+				serviceConfig: &ServiceConfig{OPCUAServer: OPCUAServerConfig{RegisterNodesInterval: tt.interval}},
+			}
+			assert.Equal(t, tt.want, d.isPolled(tt.deviceName, tt.resourceName))
+		})
+	}
+}
+
+func TestDriver_isPolled_cached(t *testing.T) {
+	sdk := &deviceServiceSDK{devices: map[string]models.Device{
+		"Test": {Name: "Test", AutoEvents: []models.AutoEvent{{Interval: "500ms", SourceName: "Speed"}}},
+	}}
+	d := &Driver{
+		sdkService:    sdk,
+		serviceConfig: &ServiceConfig{OPCUAServer: OPCUAServerConfig{RegisterNodesInterval: "1s"}},
+	}
+	require.True(t, d.isPolled("Test", "Speed"))
+
+	sdk.devices = nil
+	assert.True(t, d.isPolled("Test", "Speed"), "the device is not looked up on every read")
+
+	d.forgetRegisteredNodes("Test")
+	assert.False(t, d.isPolled("Test", "Speed"), "the polling is checked again once the device is updated")
+}
+
+func Test_nodeRegistrations(t *testing.T) {
+	client, other := &opcua.Client{}, &opcua.Client{}
+	var r nodeRegistrations
+	r.store(client, "Test/ns=2;s=Speed", ua.NewNumericNodeID(2, 1))
+	r.store(client, "Other/ns=2;s=Speed", ua.NewNumericNodeID(2, 2))
+	r.store(other, "Test/ns=2;s=Speed", ua.NewNumericNodeID(2, 3))
+
+	registered, ok := r.get(client, "Test/ns=2;s=Speed")
+	require.True(t, ok)
+	assert.Equal(t, ua.NewNumericNodeID(2, 1), registered)
+
+	taken := r.takeDevice("Test")
+	assert.Equal(t, map[*opcua.Client][]*ua.NodeID{
+		client: {ua.NewNumericNodeID(2, 1)},
+		other:  {ua.NewNumericNodeID(2, 3)},
+	}, taken)
+	_, ok = r.get(client, "Test/ns=2;s=Speed")
+	assert.False(t, ok, "the nodes of a removed device are dropped")
+
+	r.setUnsupported(client)
+	_, ok = r.get(client, "Other/ns=2;s=Speed")
+	assert.False(t, ok, "nodes are not registered when the server does not support it")
+	assert.Equal(t, []*ua.NodeID{ua.NewNumericNodeID(2, 2)}, r.takeClient(client))
+	assert.False(t, r.isUnsupported(client), "the support is checked again after a reconnect")
+	assert.Empty(t, r.takeClient(client))
+}
@@ -0,0 +1,754 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/id"
+	"github.com/gopcua/opcua/ua"
+	"github.com/spf13/cast"
+)
+
+// maxStructureDepth limits the nesting of structures resolved from the server
+const maxStructureDepth = 16
+
+// dataTypeDefinition describes how values of an OPC UA data type are encoded.
+// Structures carry the definition of every field, all other data types are
+// encoded as the built-in type they derive from.
+type dataTypeDefinition struct {
+	dataTypeID *ua.NodeID
+	builtin    ua.TypeID
+	structure  *ua.StructureDefinition
+	fields     []*dataTypeDefinition
+	// display text of the values of enumerations, nil for other data types
+	enumStrings map[int64]string
+}
+
+// dataTypeCache holds the data type definitions fetched from the servers per device,
+// along with the client which fetched them
+type dataTypeCache struct {
+	mu          sync.Mutex
+	definitions map[string]cachedDataType
+	encodings   map[string]cachedDataType
+	nodes       map[string]cachedDataType
+}
+
+type cachedDataType struct {
+	client *opcua.Client
+	def    *dataTypeDefinition
+}
+
+// rawStructure keeps the binary body of an ExtensionObject whose data type is
+// unknown to the OPC UA library, so it can be decoded with the data type definition
+type rawStructure struct {
+	body []byte
+}
+
+// Decode implements the ua.BinaryDecoder interface
+func (s *rawStructure) Decode(b []byte) (int, error) {
+	s.body = append([]byte(nil), b...)
+	return len(b), nil
+}
+
+// Encode implements the ua.BinaryEncoder interface
+func (s *rawStructure) Encode() ([]byte, error) {
+	return s.body, nil
+}
+
+func (c *dataTypeCache) definition(key string) (*dataTypeDefinition, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.definitions[key]
+	return cached.def, ok
+}
+
+func (c *dataTypeCache) encoding(key string) (*dataTypeDefinition, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.encodings[key]
+	return cached.def, ok
+}
+
+func (c *dataTypeCache) node(key string) (*dataTypeDefinition, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.nodes[key]
+	return cached.def, ok
+}
+
+func (c *dataTypeCache) storeNode(key string, client *opcua.Client, def *dataTypeDefinition) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nodes == nil {
+		c.nodes = make(map[string]cachedDataType)
+	}
+	c.nodes[key] = cachedDataType{client: client, def: def}
+}
+
+func (c *dataTypeCache) store(deviceName string, client *opcua.Client, def *dataTypeDefinition) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.definitions == nil {
+		c.definitions = make(map[string]cachedDataType)
+		c.encodings = make(map[string]cachedDataType)
+	}
+	c.definitions[deviceName+"/"+def.dataTypeID.String()] = cachedDataType{client: client, def: def}
+	if def.structure != nil && def.structure.DefaultEncodingID != nil {
+		c.encodings[deviceName+"/"+def.structure.DefaultEncodingID.String()] = cachedDataType{client: client, def: def}
+	}
+}
+
+// forgetClient drops the definitions fetched by a client, as the server may define other
+// data types once it has been restarted
+func (c *dataTypeCache) forgetClient(client *opcua.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cache := range []map[string]cachedDataType{c.definitions, c.encodings, c.nodes} {
+		for key, cached := range cache {
+			if cached.client == client {
+				delete(cache, key)
+			}
+		}
+	}
+}
+
+// nodeDataTypeDefinition returns the definition of the data type of a variable node
+func (d *Driver) nodeDataTypeDefinition(deviceName string, client *opcua.Client, nodeID *ua.NodeID) (*dataTypeDefinition, error) {
+	key := deviceName + "/" + nodeID.String()
+	if def, ok := d.dataTypes.node(key); ok {
+		return def, nil
+	}
+
+	ctx := d.serviceContext()
+	value, err := client.Node(nodeID).Attribute(ctx, ua.AttributeIDDataType)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the data type of node %s: %v", nodeID, err)
+	}
+	if value == nil {
+		return nil, fmt.Errorf("node %s has no data type", nodeID)
+	}
+	dataTypeID := value.NodeID()
+	if dataTypeID == nil {
+		return nil, fmt.Errorf("node %s has no data type", nodeID)
+	}
+
+	def, err := d.dataTypeDefinition(ctx, deviceName, client, dataTypeID, 0)
+	if err != nil {
+		return nil, err
+	}
+	d.dataTypes.storeNode(key, client, def)
+	return def, nil
+}
+
+// prepareDataType resolves the data type definition of a node before its values
+// are read, as structures and enumerations are decoded with it
+func (d *Driver) prepareDataType(deviceName string, client *opcua.Client, valueType string, attributeID ua.AttributeID, nodeID *ua.NodeID) error {
+	if attributeID != ua.AttributeIDValue {
+		return nil
+	}
+
+	switch valueType {
+	case common.ValueTypeObject:
+		_, err := d.nodeDataTypeDefinition(deviceName, client, nodeID)
+		return err
+	case common.ValueTypeString, common.ValueTypeStringArray:
+		// only needed for the display text of enumerations
+		if _, err := d.nodeDataTypeDefinition(deviceName, client, nodeID); err != nil {
+			d.Logger.Debugf("Unable to resolve the data type of node %s: %v", nodeID, err)
+		}
+	}
+	return nil
+}
+
+// resourceDataType returns the cached data type definition of the node read by the request
+func (d *Driver) resourceDataType(deviceName string, req sdkModel.CommandRequest) *dataTypeDefinition {
+	key, ok := d.valueNodeKey(deviceName, req.Attributes)
+	if !ok {
+		return nil
+	}
+	def, _ := d.dataTypes.node(key)
+	return def
+}
+
+// valueNodeKey returns the cache key of the node whose Value attribute is accessed by a resource
+func (d *Driver) valueNodeKey(deviceName string, attrs map[string]interface{}) (string, bool) {
+	if attributeID, err := getAttributeID(attrs); err != nil || attributeID != ua.AttributeIDValue {
+		return "", false
+	}
+	id, ok := d.cachedNodeID(deviceName, attrs, NODE)
+	if !ok {
+		return "", false
+	}
+	return deviceName + "/" + id.String(), true
+}
+
+// dataTypeDefinition resolves the definition of a data type through its
+// DataTypeDefinition attribute, following the supertypes of simple data types
+func (d *Driver) dataTypeDefinition(ctx context.Context, deviceName string, client *opcua.Client, dataTypeID *ua.NodeID, depth int) (*dataTypeDefinition, error) {
+	if builtin, ok := builtinTypeID(dataTypeID); ok {
+		def := &dataTypeDefinition{dataTypeID: dataTypeID, builtin: builtin}
+		if dataTypeID.IntID() == id.Enumeration {
+			def.enumStrings = make(map[int64]string)
+		}
+		return def, nil
+	}
+	if def, ok := d.dataTypes.definition(deviceName + "/" + dataTypeID.String()); ok {
+		return def, nil
+	}
+	if depth > maxStructureDepth {
+		return nil, fmt.Errorf("data type %s is nested too deeply", dataTypeID)
+	}
+
+	def := &dataTypeDefinition{dataTypeID: dataTypeID}
+	value, err := client.Node(dataTypeID).Attribute(ctx, ua.AttributeIDDataTypeDefinition)
+	var definition interface{}
+	if err == nil && value != nil {
+		if eo := value.ExtensionObject(); eo != nil {
+			definition = eo.Value
+		}
+	}
+
+	switch definition := definition.(type) {
+	case *ua.StructureDefinition:
+		if definition.StructureType > ua.StructureTypeUnion {
+			return nil, fmt.Errorf("data type %s has unsupported structure type %v", dataTypeID, definition.StructureType)
+		}
+		def.builtin = ua.TypeIDExtensionObject
+		def.structure = definition
+		def.fields = make([]*dataTypeDefinition, len(definition.Fields))
+		for i, field := range definition.Fields {
+			def.fields[i], err = d.dataTypeDefinition(ctx, deviceName, client, field.DataType, depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("field %s of data type %s: %v", field.Name, dataTypeID, err)
+			}
+		}
+		// let the library keep the body of these structures for decoding by the driver
+		if encodingID := definition.DefaultEncodingID; encodingID != nil && encodingID.Namespace() != 0 {
+			ua.RegisterExtensionObject(encodingID, new(rawStructure))
+		}
+	case *ua.EnumDefinition:
+		def.builtin = ua.TypeIDInt32
+		def.enumStrings = enumDefinitionStrings(definition)
+		if len(def.enumStrings) == 0 {
+			def.enumStrings, err = enumPropertyStrings(ctx, client, dataTypeID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		// simple data types are encoded as the data type they derive from
+		supertypes, err := client.Node(dataTypeID).ReferencedNodes(ctx, id.HasSubtype, ua.BrowseDirectionInverse, ua.NodeClassDataType, false)
+		if err != nil {
+			return nil, fmt.Errorf("unable to browse the supertype of data type %s: %v", dataTypeID, err)
+		}
+		if len(supertypes) == 0 {
+			return nil, fmt.Errorf("unable to resolve data type %s", dataTypeID)
+		}
+		supertype, err := d.dataTypeDefinition(ctx, deviceName, client, supertypes[0].ID, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		def.builtin = supertype.builtin
+		def.structure = supertype.structure
+		def.fields = supertype.fields
+		if supertype.enumStrings != nil {
+			// enumerations without a DataTypeDefinition publish their values as properties
+			def.enumStrings, err = enumPropertyStrings(ctx, client, dataTypeID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	d.dataTypes.store(deviceName, client, def)
+	return def, nil
+}
+
+// builtinTypeID returns the built-in type of the data types defined in namespace 0
+func builtinTypeID(dataTypeID *ua.NodeID) (ua.TypeID, bool) {
+	if dataTypeID.Namespace() != 0 {
+		return 0, false
+	}
+	switch intID := dataTypeID.IntID(); {
+	case intID >= uint32(ua.TypeIDBoolean) && intID <= uint32(ua.TypeIDDiagnosticInfo):
+		return ua.TypeID(intID), true
+	case intID == id.Enumeration:
+		return ua.TypeIDInt32, true
+	}
+	return 0, false
+}
+
+// structureReading converts an ExtensionObject reading into a map of its field values
+func (d *Driver) structureReading(deviceName string, reading interface{}) (interface{}, error) {
+	if array, ok := reading.([]*ua.ExtensionObject); ok {
+		values := make([]interface{}, len(array))
+		for i, eo := range array {
+			value, err := d.structureReading(deviceName, eo)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %v", i, err)
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+
+	eo, ok := reading.(*ua.ExtensionObject)
+	if !ok || eo == nil {
+		return reading, nil
+	}
+	raw, ok := eo.Value.(*rawStructure)
+	if !ok {
+		return reading, nil
+	}
+
+	def, ok := d.dataTypes.encoding(deviceName + "/" + eo.TypeID.NodeID.String())
+	if !ok {
+		return nil, fmt.Errorf("no data type definition found for encoding %s", eo.TypeID.NodeID)
+	}
+	return decodeStructure(def, raw.body)
+}
+
+// decodeStructure decodes the binary body of a structure
+func decodeStructure(def *dataTypeDefinition, body []byte) (map[string]interface{}, error) {
+	buf := ua.NewBuffer(body)
+	value := readStructure(buf, def)
+	if buf.Error() != nil {
+		return nil, fmt.Errorf("unable to decode structure %s: %v", def.dataTypeID, buf.Error())
+	}
+	return value, nil
+}
+
+func readStructure(buf *ua.Buffer, def *dataTypeDefinition) map[string]interface{} {
+	value := make(map[string]interface{})
+	fields := def.structure.Fields
+
+	switch def.structure.StructureType {
+	case ua.StructureTypeUnion:
+		switchField := buf.ReadUint32()
+		if switchField > 0 && int(switchField) <= len(fields) {
+			i := switchField - 1
+			value[fields[i].Name] = readField(buf, fields[i], def.fields[i])
+		}
+	case ua.StructureTypeStructureWithOptionalFields:
+		mask := buf.ReadUint32()
+		bit := 0
+		for i, field := range fields {
+			if field.IsOptional {
+				present := mask&(1<<bit) != 0
+				bit++
+				if !present {
+					continue
+				}
+			}
+			value[field.Name] = readField(buf, field, def.fields[i])
+		}
+	default:
+		for i, field := range fields {
+			value[field.Name] = readField(buf, field, def.fields[i])
+		}
+	}
+
+	return value
+}
+
+func readField(buf *ua.Buffer, field *ua.StructureField, def *dataTypeDefinition) interface{} {
+	if field.ValueRank < 1 {
+		return readValue(buf, def)
+	}
+
+	n := buf.ReadInt32()
+	if n < 0 {
+		return nil
+	}
+	values := make([]interface{}, 0, n)
+	for i := int32(0); i < n && buf.Error() == nil; i++ {
+		values = append(values, readValue(buf, def))
+	}
+	return values
+}
+
+func readValue(buf *ua.Buffer, def *dataTypeDefinition) interface{} {
+	if def.structure != nil && def.builtin == ua.TypeIDExtensionObject {
+		return readStructure(buf, def)
+	}
+
+	switch def.builtin {
+	case ua.TypeIDBoolean:
+		return buf.ReadBool()
+	case ua.TypeIDSByte:
+		return buf.ReadInt8()
+	case ua.TypeIDByte:
+		return buf.ReadByte()
+	case ua.TypeIDInt16:
+		return buf.ReadInt16()
+	case ua.TypeIDUint16:
+		return buf.ReadUint16()
+	case ua.TypeIDInt32:
+		return buf.ReadInt32()
+	case ua.TypeIDUint32:
+		return buf.ReadUint32()
+	case ua.TypeIDInt64:
+		return buf.ReadInt64()
+	case ua.TypeIDUint64:
+		return buf.ReadUint64()
+	case ua.TypeIDFloat:
+		return buf.ReadFloat32()
+	case ua.TypeIDDouble:
+		return buf.ReadFloat64()
+	case ua.TypeIDString:
+		return buf.ReadString()
+	case ua.TypeIDDateTime:
+		return buf.ReadTime()
+	case ua.TypeIDByteString:
+		return buf.ReadBytes()
+	case ua.TypeIDXMLElement:
+		return buf.ReadString()
+	case ua.TypeIDStatusCode:
+		return buf.ReadUint32()
+	}
+
+	var value interface{}
+	switch def.builtin {
+	case ua.TypeIDGUID:
+		value = new(ua.GUID)
+	case ua.TypeIDNodeID:
+		value = new(ua.NodeID)
+	case ua.TypeIDExpandedNodeID:
+		value = new(ua.ExpandedNodeID)
+	case ua.TypeIDQualifiedName:
+		value = new(ua.QualifiedName)
+	case ua.TypeIDLocalizedText:
+		value = new(ua.LocalizedText)
+	case ua.TypeIDExtensionObject:
+		value = new(ua.ExtensionObject)
+	case ua.TypeIDDataValue:
+		value = new(ua.DataValue)
+	case ua.TypeIDVariant:
+		value = new(ua.Variant)
+	case ua.TypeIDDiagnosticInfo:
+		value = new(ua.DiagnosticInfo)
+	default:
+		return nil
+	}
+	buf.ReadStruct(value)
+	return objectValue(value)
+}
+
+// encodeStructure encodes the field values of an Object into the binary body of a structure
+func encodeStructure(def *dataTypeDefinition, value map[string]interface{}) ([]byte, error) {
+	buf := ua.NewBuffer(nil)
+	if err := writeStructure(buf, def, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), buf.Error()
+}
+
+// newStructureWriteValue encodes the Object value of a write command with the
+// data type definition of the node
+func (d *Driver) newStructureWriteValue(deviceName string, client *opcua.Client, nodeID *ua.NodeID, value interface{}) (*ua.Variant, error) {
+	def, err := d.nodeDataTypeDefinition(deviceName, client, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if def.structure == nil || def.structure.DefaultEncodingID == nil {
+		return nil, fmt.Errorf("data type %s of node %s is not a structure", def.dataTypeID, nodeID)
+	}
+
+	if array, ok := value.([]interface{}); ok {
+		eos := make([]*ua.ExtensionObject, len(array))
+		for i, element := range array {
+			eos[i], err = newStructureExtensionObject(def, element)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %v", i, err)
+			}
+		}
+		return ua.NewVariant(eos)
+	}
+
+	eo, err := newStructureExtensionObject(def, value)
+	if err != nil {
+		return nil, err
+	}
+	return ua.NewVariant(eo)
+}
+
+// newStructureExtensionObject wraps the field values of an Object into an ExtensionObject of the data type
+func newStructureExtensionObject(def *dataTypeDefinition, value interface{}) (*ua.ExtensionObject, error) {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("structure %s requires an object value, got %T", def.dataTypeID, value)
+	}
+	body, err := encodeStructure(def, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ua.ExtensionObject{
+		EncodingMask: ua.ExtensionObjectBinary,
+		TypeID:       ua.NewExpandedNodeID(def.structure.DefaultEncodingID, "", 0),
+		Value:        &rawStructure{body: body},
+	}, nil
+}
+
+func writeStructure(buf *ua.Buffer, def *dataTypeDefinition, value map[string]interface{}) error {
+	fields := def.structure.Fields
+
+	switch def.structure.StructureType {
+	case ua.StructureTypeUnion:
+		for i, field := range fields {
+			if fieldValue, ok := value[field.Name]; ok {
+				buf.WriteUint32(uint32(i + 1)) // #nosec G115
+				return writeField(buf, field, def.fields[i], fieldValue)
+			}
+		}
+		buf.WriteUint32(0)
+		return nil
+	case ua.StructureTypeStructureWithOptionalFields:
+		var mask uint32
+		bit := 0
+		for _, field := range fields {
+			if field.IsOptional {
+				if _, ok := value[field.Name]; ok {
+					mask |= 1 << bit
+				}
+				bit++
+			}
+		}
+		buf.WriteUint32(mask)
+	}
+
+	for i, field := range fields {
+		fieldValue, ok := value[field.Name]
+		if !ok {
+			if field.IsOptional && def.structure.StructureType == ua.StructureTypeStructureWithOptionalFields {
+				continue
+			}
+			return fmt.Errorf("field %s of structure %s is missing", field.Name, def.dataTypeID)
+		}
+		if err := writeField(buf, field, def.fields[i], fieldValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeField(buf *ua.Buffer, field *ua.StructureField, def *dataTypeDefinition, value interface{}) error {
+	if field.ValueRank < 1 {
+		if err := writeValue(buf, def, value); err != nil {
+			return fmt.Errorf("field %s: %v", field.Name, err)
+		}
+		return nil
+	}
+
+	if value == nil {
+		buf.WriteInt32(-1)
+		return nil
+	}
+	array := reflect.ValueOf(value)
+	if array.Kind() != reflect.Slice && array.Kind() != reflect.Array {
+		return fmt.Errorf("field %s requires an array value, got %T", field.Name, value)
+	}
+	buf.WriteInt32(int32(array.Len())) // #nosec G115
+	for i := 0; i < array.Len(); i++ {
+		if err := writeValue(buf, def, array.Index(i).Interface()); err != nil {
+			return fmt.Errorf("field %s element %d: %v", field.Name, i, err)
+		}
+	}
+	return nil
+}
+
+func writeValue(buf *ua.Buffer, def *dataTypeDefinition, value interface{}) error {
+	if def.structure != nil && def.builtin == ua.TypeIDExtensionObject {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("structure %s requires an object value, got %T", def.dataTypeID, value)
+		}
+		return writeStructure(buf, def, fields)
+	}
+
+	var err error
+	switch def.builtin {
+	case ua.TypeIDBoolean:
+		var v bool
+		v, err = cast.ToBoolE(value)
+		buf.WriteBool(v)
+	case ua.TypeIDSByte:
+		var v int8
+		v, err = cast.ToInt8E(value)
+		buf.WriteInt8(v)
+	case ua.TypeIDByte:
+		var v uint8
+		v, err = cast.ToUint8E(value)
+		buf.WriteUint8(v)
+	case ua.TypeIDInt16:
+		var v int16
+		v, err = cast.ToInt16E(value)
+		buf.WriteInt16(v)
+	case ua.TypeIDUint16:
+		var v uint16
+		v, err = cast.ToUint16E(value)
+		buf.WriteUint16(v)
+	case ua.TypeIDInt32:
+		var v int32
+		v, err = cast.ToInt32E(value)
+		buf.WriteInt32(v)
+	case ua.TypeIDUint32, ua.TypeIDStatusCode:
+		var v uint32
+		v, err = cast.ToUint32E(value)
+		buf.WriteUint32(v)
+	case ua.TypeIDInt64:
+		var v int64
+		v, err = cast.ToInt64E(value)
+		buf.WriteInt64(v)
+	case ua.TypeIDUint64:
+		var v uint64
+		v, err = cast.ToUint64E(value)
+		buf.WriteUint64(v)
+	case ua.TypeIDFloat:
+		var v float32
+		v, err = cast.ToFloat32E(value)
+		buf.WriteFloat32(v)
+	case ua.TypeIDDouble:
+		var v float64
+		v, err = cast.ToFloat64E(value)
+		buf.WriteFloat64(v)
+	case ua.TypeIDString, ua.TypeIDXMLElement:
+		var v string
+		v, err = cast.ToStringE(value)
+		buf.WriteString(v)
+	case ua.TypeIDDateTime:
+		var v time.Time
+		v, err = cast.ToTimeE(value)
+		buf.WriteTime(v)
+	case ua.TypeIDByteString:
+		var v []byte
+		v, err = byteStringValue(value)
+		buf.WriteByteString(v)
+	case ua.TypeIDGUID:
+		guid := ua.NewGUID(cast.ToString(value))
+		if guid == nil {
+			return fmt.Errorf("invalid GUID %v", value)
+		}
+		buf.WriteStruct(guid)
+	case ua.TypeIDNodeID:
+		var nodeID *ua.NodeID
+		nodeID, err = ua.ParseNodeID(cast.ToString(value))
+		if err == nil {
+			buf.WriteStruct(nodeID)
+		}
+	case ua.TypeIDQualifiedName:
+		buf.WriteStruct(&ua.QualifiedName{Name: cast.ToString(value)})
+	case ua.TypeIDLocalizedText:
+		buf.WriteStruct(ua.NewLocalizedText(cast.ToString(value)))
+	case ua.TypeIDVariant:
+		var v *ua.Variant
+		v, err = ua.NewVariant(value)
+		if err == nil {
+			buf.WriteStruct(v)
+		}
+	default:
+		return fmt.Errorf("data type %s cannot be written", def.dataTypeID)
+	}
+	if err != nil {
+		return err
+	}
+	return buf.Error()
+}
+
+// byteStringValue accepts a ByteString either as bytes or as base64 encoded string
+func byteStringValue(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return base64.StdEncoding.DecodeString(v)
+	}
+	return nil, fmt.Errorf("unable to cast %#v of type %T to ByteString", value, value)
+}
+
+// objectValue converts OPC UA values into plain values of an Object reading.
+// Structures known to the OPC UA library are converted into maps of their fields.
+func objectValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case *ua.LocalizedText:
+		return v.Text
+	case *ua.QualifiedName:
+		return v.Name
+	case *ua.NodeID:
+		return v.String()
+	case *ua.ExpandedNodeID:
+		return v.String()
+	case *ua.GUID:
+		return v.String()
+	case ua.StatusCode:
+		return uint32(v)
+	case ua.XMLElement:
+		return string(v)
+	case *ua.XMLElement:
+		return string(*v)
+	case *ua.Variant:
+		return objectValue(v.Value())
+	case *ua.DataValue:
+		if v.Value == nil {
+			return nil
+		}
+		return objectValue(v.Value.Value())
+	case *ua.ExtensionObject:
+		return objectValue(v.Value)
+	case *rawStructure:
+		return v.body
+	case time.Time, []byte, ua.ByteArray, map[string]interface{}:
+		return value
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]interface{}, rv.Len())
+		for i := range values {
+			values[i] = objectValue(rv.Index(i).Interface())
+		}
+		return values
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		if rv.Elem().Kind() == reflect.Struct {
+			return structValue(rv.Elem())
+		}
+		return objectValue(rv.Elem().Interface())
+	case reflect.Struct:
+		return structValue(rv)
+	}
+	return value
+}
+
+func structValue(rv reflect.Value) map[string]interface{} {
+	fields := make(map[string]interface{}, rv.NumField())
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if !field.IsExported() || strings.HasSuffix(field.Name, "EncodingMask") {
+			continue
+		}
+		fields[field.Name] = objectValue(rv.Field(i).Interface())
+	}
+	return fields
+}
@@ -0,0 +1,210 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"testing"
+	"time"
+
+	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func builtinDefinition(typeID ua.TypeID) *dataTypeDefinition {
+	return &dataTypeDefinition{dataTypeID: ua.NewNumericNodeID(0, uint32(typeID)), builtin: typeID}
+}
+
+func structureDefinition(structureType ua.StructureType, names []string, optional []bool, valueRanks []int32, fields ...*dataTypeDefinition) *dataTypeDefinition {
+	structure := &ua.StructureDefinition{
+		DefaultEncodingID: ua.NewNumericNodeID(2, 5001),
+		StructureType:     structureType,
+	}
+	for i, field := range fields {
+		structure.Fields = append(structure.Fields, &ua.StructureField{
+			Name:       names[i],
+			DataType:   field.dataTypeID,
+			ValueRank:  valueRanks[i],
+			IsOptional: optional[i],
+		})
+	}
+	return &dataTypeDefinition{
+		dataTypeID: ua.NewNumericNodeID(2, 3001),
+		builtin:    ua.TypeIDExtensionObject,
+		structure:  structure,
+		fields:     fields,
+	}
+}
+
+func TestStructureRoundTrip(t *testing.T) {
+	point := structureDefinition(ua.StructureTypeStructure,
+		[]string{"X", "Y"}, []bool{false, false}, []int32{-1, -1},
+		builtinDefinition(ua.TypeIDDouble), builtinDefinition(ua.TypeIDDouble))
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		def   *dataTypeDefinition
+		value map[string]interface{}
+		want  map[string]interface{}
+	}{
+		{
+			name: "structure with builtin and nested fields",
+			def: structureDefinition(ua.StructureTypeStructure,
+				[]string{"Name", "Count", "Time", "Label", "Position"},
+				[]bool{false, false, false, false, false},
+				[]int32{-1, -1, -1, -1, -1},
+				builtinDefinition(ua.TypeIDString), builtinDefinition(ua.TypeIDUint16),
+				builtinDefinition(ua.TypeIDDateTime), builtinDefinition(ua.TypeIDLocalizedText), point),
+			value: map[string]interface{}{
+				"Name": "pump", "Count": 3.0, "Time": "2024-05-01T12:00:00Z", "Label": "Pump 1",
+				"Position": map[string]interface{}{"X": 1.5, "Y": -2},
+			},
+			want: map[string]interface{}{
+				"Name": "pump", "Count": uint16(3), "Time": timestamp, "Label": "Pump 1",
+				"Position": map[string]interface{}{"X": 1.5, "Y": -2.0},
+			},
+		},
+		{
+			name: "array field",
+			def: structureDefinition(ua.StructureTypeStructure,
+				[]string{"Values"}, []bool{false}, []int32{1}, builtinDefinition(ua.TypeIDInt32)),
+			value: map[string]interface{}{"Values": []interface{}{1, 2, 3}},
+			want:  map[string]interface{}{"Values": []interface{}{int32(1), int32(2), int32(3)}},
+		},
+		{
+			name: "optional field omitted",
+			def: structureDefinition(ua.StructureTypeStructureWithOptionalFields,
+				[]string{"Required", "Optional", "Present"}, []bool{false, true, true}, []int32{-1, -1, -1},
+				builtinDefinition(ua.TypeIDBoolean), builtinDefinition(ua.TypeIDString), builtinDefinition(ua.TypeIDByte)),
+			value: map[string]interface{}{"Required": true, "Present": 7},
+			want:  map[string]interface{}{"Required": true, "Present": uint8(7)},
+		},
+		{
+			name: "union",
+			def: structureDefinition(ua.StructureTypeUnion,
+				[]string{"Number", "Text"}, []bool{false, false}, []int32{-1, -1},
+				builtinDefinition(ua.TypeIDInt64), builtinDefinition(ua.TypeIDString)),
+			value: map[string]interface{}{"Text": "on"},
+			want:  map[string]interface{}{"Text": "on"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := encodeStructure(tt.def, tt.value)
+			require.NoError(t, err)
+			got, err := decodeStructure(tt.def, body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEncodeStructure_errors(t *testing.T) {
+	def := structureDefinition(ua.StructureTypeStructure,
+		[]string{"Count", "Data"}, []bool{false, false}, []int32{-1, -1},
+		builtinDefinition(ua.TypeIDInt16), builtinDefinition(ua.TypeIDByteString))
+
+	tests := []struct {
+		name  string
+		value map[string]interface{}
+	}{
+		{"missing field", map[string]interface{}{"Count": 1}},
+		{"invalid number", map[string]interface{}{"Count": "many", "Data": ""}},
+		{"invalid base64", map[string]interface{}{"Count": 1, "Data": "%%%"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := encodeStructure(def, tt.value)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDecodeStructure_truncated(t *testing.T) {
+	def := structureDefinition(ua.StructureTypeStructure,
+		[]string{"Value"}, []bool{false}, []int32{-1}, builtinDefinition(ua.TypeIDUint64))
+
+	_, err := decodeStructure(def, []byte{0x01, 0x02})
+	assert.Error(t, err)
+}
+
+func TestDriver_structureReading(t *testing.T) {
+	def := structureDefinition(ua.StructureTypeStructure,
+		[]string{"Level"}, []bool{false}, []int32{-1}, builtinDefinition(ua.TypeIDFloat))
+	body, err := encodeStructure(def, map[string]interface{}{"Level": 0.5})
+	require.NoError(t, err)
+
+	d := &Driver{}
+	d.dataTypes.store("Test", nil, def)
+	eo := &ua.ExtensionObject{
+		TypeID: ua.NewExpandedNodeID(def.structure.DefaultEncodingID, "", 0),
+		Value:  &rawStructure{body: body},
+	}
+
+	got, err := d.structureReading("Test", eo)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"Level": float32(0.5)}, got)
+
+	got, err = d.structureReading("Test", []*ua.ExtensionObject{eo})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"Level": float32(0.5)}}, got)
+
+	_, err = d.structureReading("Other", eo)
+	assert.Error(t, err)
+}
+
+func TestNewResult_object(t *testing.T) {
+	req := sdkModel.CommandRequest{DeviceResourceName: "Status", Type: common.ValueTypeObject}
+
+	tests := []struct {
+		name    string
+		reading interface{}
+		want    interface{}
+	}{
+		{"decoded structure", map[string]interface{}{"Level": 1}, map[string]interface{}{"Level": 1}},
+		{"library structure", &ua.Range{Low: 1, High: 10}, map[string]interface{}{"Low": 1.0, "High": 10.0}},
+		{
+			"nested OPC UA types",
+			&ua.EUInformation{NamespaceURI: "uri", UnitID: 4408652, DisplayName: ua.NewLocalizedText("°C"), Description: ua.NewLocalizedText("degree Celsius")},
+			map[string]interface{}{"NamespaceURI": "uri", "UnitID": int32(4408652), "DisplayName": "°C", "Description": "degree Celsius"},
+		},
+		{"node id", ua.NewNumericNodeID(2, 10), "ns=2;i=10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newResult(req, tt.reading)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Value)
+		})
+	}
+}
+
+func TestDriver_forgetNodes_dataTypes(t *testing.T) {
+	def := structureDefinition(ua.StructureTypeStructure,
+		[]string{"Level"}, []bool{false}, []int32{-1}, builtinDefinition(ua.TypeIDFloat))
+	client, other := &opcua.Client{}, &opcua.Client{}
+
+	d := &Driver{}
+	d.dataTypes.store("Test", client, def)
+	d.dataTypes.storeNode("Test/ns=2;s=Tank", client, def)
+	d.dataTypes.store("Other", other, def)
+
+	d.forgetNodes(client)
+
+	_, ok := d.dataTypes.definition("Test/" + def.dataTypeID.String())
+	assert.False(t, ok, "definitions are fetched again after a reconnect")
+	_, ok = d.dataTypes.encoding("Test/" + def.structure.DefaultEncodingID.String())
+	assert.False(t, ok)
+	_, ok = d.dataTypes.node("Test/ns=2;s=Tank")
+	assert.False(t, ok)
+	_, ok = d.dataTypes.definition("Other/" + def.dataTypeID.String())
+	assert.True(t, ok, "definitions of other clients are kept")
+}
@@ -34,8 +34,8 @@ func (d *Driver) startSubscriptionListener() error {
 		return nil
 	}
 
-	// Create a cancelable context for Writable configuration
-	ctxBg := context.Background()
+	// Create a cancelable context for Writable configuration, also cancelled when the service stops
+	ctxBg := d.serviceContext()
 	ctx, cancel := context.WithCancel(ctxBg)
 	d.ctxCancel = cancel
 
@@ -43,19 +43,22 @@ func (d *Driver) startSubscriptionListener() error {
 	if err != nil {
 		return err
 	}
+	settings, xerr := fetchDeviceSettings(device.Protocols)
+	if xerr != nil {
+		return xerr
+	}
 
-	client, err := d.getClient(device)
+	states := make(chan opcua.ConnState, 8)
+	client, err := d.getClient(device, states)
 	if err != nil {
 		return err
 	}
 
+	defer d.releaseClient(client)
 	if err := client.Connect(ctx); err != nil {
 		d.Logger.Warnf("[Incoming listener] Failed to connect OPCUA client, %s", err)
 		return err
 	}
-	defer func(client *opcua.Client, ctx context.Context) {
-		_ = client.Close(ctx)
-	}(client, ctx)
 
 	notifyCh := make(chan *opcua.PublishNotificationData)
 	sub, err := client.Subscribe(ctx, &opcua.SubscriptionParameters{
@@ -66,22 +69,30 @@ func (d *Driver) startSubscriptionListener() error {
 	}
 	defer func(sub *opcua.Subscription, ctx context.Context) {
 		_ = sub.Cancel(ctx)
+		d.monitoredItems.removeSubscription(sub)
 	}(sub, ctx)
 
-	if err := d.configureMonitoredItems(sub, resources, deviceName); err != nil {
+	if err := d.configureMonitoredItems(client, sub, resources, deviceName); err != nil {
 		return err
 	}
 
 	// go sub.Run(ctx) // start Publish loop
 
+	var outage connectionOutage
+	history := clientHistory{client: client}
+
 	// read from subscription's notification channel until ctx is cancelled
 	for {
 		select {
 		// context return
 		case <-ctx.Done():
 			return nil
+		case state := <-states:
+			d.handleConnectionState(state, &outage, client, settings, history, deviceName, resources)
 			// receive Publish Notification Data
 		case res := <-notifyCh:
+			// the values missed during an outage are published before the live values
+			d.endOutage(&outage, client, settings, history, deviceName, resources)
 			if res.Error != nil {
 				d.Logger.Debug(res.Error.Error())
 				continue
@@ -90,7 +101,7 @@ func (d *Driver) startSubscriptionListener() error {
 			switch x := res.Value.(type) {
 			// result type: DateChange StatusChange
 			case *ua.DataChangeNotification:
-				d.handleDataChange(x)
+				d.handleDataChange(sub, x)
 
 			// case *ua.EventNotificationList:
 			// 	for _, item := range x.Events {
@@ -107,7 +118,7 @@ func (d *Driver) startSubscriptionListener() error {
 	}
 }
 
-func (d *Driver) getClient(device models.Device) (*opcua.Client, error) {
+func (d *Driver) getClient(device models.Device, states chan<- opcua.ConnState) (*opcua.Client, error) {
 	var (
 		policy   = d.serviceConfig.OPCUAServer.Policy
 		mode     = d.serviceConfig.OPCUAServer.Mode
@@ -119,8 +130,13 @@ func (d *Driver) getClient(device models.Device) (*opcua.Client, error) {
 	if xerr != nil {
 		return nil, xerr
 	}
+	settings, xerr := fetchDeviceSettings(device.Protocols)
+	if xerr != nil {
+		return nil, xerr
+	}
 
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(d.serviceContext(), settings.RequestTimeout)
+	defer cancel()
 	endpoints, err := opcua.GetEndpoints(ctx, endpoint)
 	if err != nil {
 		return nil, err
@@ -141,42 +157,56 @@ func (d *Driver) getClient(device models.Device) (*opcua.Client, error) {
 		opcua.PrivateKeyFile(keyFile),
 		opcua.AuthAnonymous(),
 		opcua.SecurityFromEndpoint(ep, ua.UserTokenTypeAnonymous),
+		opcua.RequestTimeout(settings.RequestTimeout),
+	}
+	if settings.SessionTimeout > 0 {
+		opts = append(opts, opcua.SessionTimeout(settings.SessionTimeout))
 	}
 
-	return opcua.NewClient(ep.EndpointURL, opts...)
+	return d.newWatchedClient(ep.EndpointURL, states, opts...)
 }
 
-func (d *Driver) configureMonitoredItems(sub *opcua.Subscription, resources, deviceName string) error {
-
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	for i, node := range strings.Split(resources, ",") {
+func (d *Driver) configureMonitoredItems(client *opcua.Client, sub *opcua.Subscription, resources, deviceName string) error {
+	for _, node := range strings.Split(resources, ",") {
 		deviceResource, ok := d.sdkService.DeviceResource(deviceName, node)
 		if !ok {
 			return fmt.Errorf("[Incoming listener] Unable to find device resource with name %s", node)
 		}
 
-		opcuaNodeID, err := getNodeID(deviceResource.Attributes, NODE)
+		id, err := d.resourceNodeID(deviceName, client, deviceResource.Attributes, NODE)
+		if err != nil {
+			return err
+		}
+
+		attributeID, err := getAttributeID(deviceResource.Attributes)
 		if err != nil {
 			return err
 		}
 
-		id, err := ua.ParseNodeID(opcuaNodeID)
+		indexRange, err := getIndexRange(deviceResource.Attributes)
 		if err != nil {
 			return err
 		}
 
-		// arbitrary client handle for the monitoring item
-		handle := uint32(i + 42) // #nosec G115
-		// map the client handle so we know what the value returned represents
-		d.resourceMap[handle] = node
-		miCreateRequest := opcua.NewMonitoredItemCreateRequestWithDefaults(id, ua.AttributeIDValue, handle)
-		ctx := context.Background()
+		if err := d.prepareDataType(deviceName, client, deviceResource.Properties.ValueType, attributeID, id); err != nil {
+			return err
+		}
+		d.prepareUnits(deviceName, client, node, deviceResource.Attributes)
+
+		// register the client handle so we know what the value returned represents
+		handle := d.monitoredItems.add(sub, deviceName, node)
+		miCreateRequest := opcua.NewMonitoredItemCreateRequestWithDefaults(id, attributeID, handle)
+		miCreateRequest.ItemToMonitor.IndexRange = indexRange
+		ctx := d.serviceContext()
 		res, err := sub.Monitor(ctx, ua.TimestampsToReturnBoth, miCreateRequest)
-		if err != nil || res.Results[0].StatusCode != ua.StatusOK {
+		if err != nil {
+			d.monitoredItems.remove(sub, handle)
 			return err
 		}
+		if status := res.Results[0].StatusCode; status != ua.StatusOK {
+			d.monitoredItems.remove(sub, handle)
+			return fmt.Errorf("[Incoming listener] Unable to monitor %s: %v", node, status)
+		}
 
 		d.Logger.Infof("[Incoming listener] Start incoming data listening for %s.", node)
 	}
@@ -184,22 +214,43 @@ func (d *Driver) configureMonitoredItems(sub *opcua.Subscription, resources, dev
 	return nil
 }
 
-func (d *Driver) handleDataChange(dcn *ua.DataChangeNotification) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
+func (d *Driver) handleDataChange(sub *opcua.Subscription, dcn *ua.DataChangeNotification) {
+	var deviceNames []string
+	values := make(map[string][]*sdkModels.CommandValue)
 	for _, item := range dcn.MonitoredItems {
-		data := item.Value.Value.Value()
-		nodeName := d.resourceMap[item.ClientHandle]
-		if err := d.onIncomingDataReceived(data, nodeName); err != nil {
-			d.Logger.Errorf("%v", err)
+		monitored, ok := d.monitoredItems.lookup(sub, item.ClientHandle)
+		if !ok {
+			d.Logger.Warnf("[Incoming listener] Incoming reading ignored. Unknown client handle %d", item.ClientHandle)
+			continue
+		}
+		if !d.isNewValue(monitored.deviceName, monitored.resourceName, item.Value) {
+			d.Logger.Debugf("[Incoming listener] Incoming reading of %s ignored, already published.", monitored.resourceName)
+			continue
+		}
+		result := d.onIncomingDataReceived(monitored.deviceName, item.Value, monitored.resourceName)
+		if result == nil {
+			continue
+		}
+		if _, ok := values[monitored.deviceName]; !ok {
+			deviceNames = append(deviceNames, monitored.deviceName)
+		}
+		values[monitored.deviceName] = append(values[monitored.deviceName], result)
+	}
+
+	for _, deviceName := range deviceNames {
+		for _, asyncValues := range d.groupValues(deviceName, values[deviceName]) {
+			d.publishAsync(asyncValues)
 		}
 	}
 }
 
-func (d *Driver) onIncomingDataReceived(data interface{}, nodeResourceName string) error {
-	deviceName := d.serviceConfig.OPCUAServer.DeviceName
-	reading := data
+// onIncomingDataReceived returns the reading of a value received for a resource,
+// or nil when the value is ignored
+func (d *Driver) onIncomingDataReceived(deviceName string, dataValue *ua.DataValue, nodeResourceName string) *sdkModels.CommandValue {
+	var data interface{}
+	if dataValue.Value != nil {
+		data = dataValue.Value.Value()
+	}
 
 	deviceResource, ok := d.sdkService.DeviceResource(deviceName, nodeResourceName)
 	if !ok {
@@ -209,23 +260,17 @@ func (d *Driver) onIncomingDataReceived(data interface{}, nodeResourceName strin
 
 	req := sdkModels.CommandRequest{
 		DeviceResourceName: nodeResourceName,
+		Attributes:         deviceResource.Attributes,
 		Type:               deviceResource.Properties.ValueType,
 	}
 
-	result, err := newResult(req, reading)
+	result, err := d.newDataValueResult(deviceName, req, dataValue)
 	if err != nil {
-		d.Logger.Warnf("[Incoming listener] Incoming reading ignored. name=%v deviceResource=%v value=%v", deviceName, nodeResourceName, data)
+		d.Logger.Warnf("[Incoming listener] Incoming reading ignored. name=%v deviceResource=%v value=%v: %v", deviceName, nodeResourceName, data, err)
 		return nil
 	}
 
-	asyncValues := &sdkModels.AsyncValues{
-		DeviceName:    deviceName,
-		CommandValues: []*sdkModels.CommandValue{result},
-	}
-
 	d.Logger.Infof("[Incoming listener] Incoming reading received: name=%v deviceResource=%v value=%v", deviceName, nodeResourceName, data)
 
-	d.AsyncCh <- asyncValues
-
-	return nil
+	return result
 }
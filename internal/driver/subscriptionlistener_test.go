@@ -62,7 +62,7 @@ func TestDriver_getClient(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			d := NewProtocolDriver().(*Driver)
 			d.serviceConfig = &ServiceConfig{}
-			_, err := d.getClient(tt.device)
+			_, err := d.getClient(tt.device, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Driver.getClient() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -76,7 +76,7 @@ func TestDriver_getClient(t *testing.T) {
 //func TestDriver_handleDataChange(t *testing.T) {
 //	tests := []struct {
 //		name        string
-//		resourceMap map[uint32]string
+//		resources   []string
 //		dcn         *ua.DataChangeNotification
 //	}{
 //		{
@@ -85,10 +85,10 @@ func TestDriver_getClient(t *testing.T) {
 //		},
 //		{
 //			name:        "OK - call onIncomingDataReceived",
-//			resourceMap: map[uint32]string{123456: "TestResource"},
+//			resources:   []string{"TestResource"},
 //			dcn: &ua.DataChangeNotification{
 //				MonitoredItems: []*ua.MonitoredItemNotification{
-//					{ClientHandle: 123456, Value: &ua.DataValue{Value: ua.MustVariant("42")}},
+//					{ClientHandle: 1, Value: &ua.DataValue{Value: ua.MustVariant("42")}},
 //				},
 //			},
 //		},
@@ -97,7 +97,11 @@ func TestDriver_getClient(t *testing.T) {
 //		t.Run(tt.name, func(t *testing.T) {
 //			d := NewProtocolDriver().(*Driver)
 //			d.serviceConfig = &ServiceConfig{}
-//			d.handleDataChange(tt.dcn)
+//			sub := &opcua.Subscription{}
+//			for _, resource := range tt.resources {
+//				d.monitoredItems.add(sub, "TestDevice", resource)
+//			}
+//			d.handleDataChange(sub, tt.dcn)
 //		})
 //	}
 //}
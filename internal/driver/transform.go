@@ -0,0 +1,225 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/spf13/cast"
+)
+
+// valueTypeRanges holds the range of the numeric value types which can be transformed
+var valueTypeRanges = map[string][2]float64{
+	common.ValueTypeUint8:   {0, math.MaxUint8},
+	common.ValueTypeUint16:  {0, math.MaxUint16},
+	common.ValueTypeUint32:  {0, math.MaxUint32},
+	common.ValueTypeUint64:  {0, math.MaxUint64},
+	common.ValueTypeInt8:    {math.MinInt8, math.MaxInt8},
+	common.ValueTypeInt16:   {math.MinInt16, math.MaxInt16},
+	common.ValueTypeInt32:   {math.MinInt32, math.MaxInt32},
+	common.ValueTypeInt64:   {math.MinInt64, math.MaxInt64},
+	common.ValueTypeFloat32: {-math.MaxFloat32, math.MaxFloat32},
+	common.ValueTypeFloat64: {-math.MaxFloat64, math.MaxFloat64},
+}
+
+// valueTypeBits holds the width of the integer value types, whose bit patterns are masked and shifted
+var valueTypeBits = map[string]int{
+	common.ValueTypeUint8:  8,
+	common.ValueTypeUint16: 16,
+	common.ValueTypeUint32: 32,
+	common.ValueTypeUint64: 64,
+	common.ValueTypeInt8:   8,
+	common.ValueTypeInt16:  16,
+	common.ValueTypeInt32:  32,
+	common.ValueTypeInt64:  64,
+}
+
+// dataTransform reports whether the driver applies the transformations of the device resources
+func (d *Driver) dataTransform() bool {
+	return d.serviceConfig != nil && d.serviceConfig.OPCUAServer.DataTransform
+}
+
+// resourceProperties returns the properties of a device resource when the driver transforms its values
+func (d *Driver) resourceProperties(deviceName, resourceName string) (models.ResourceProperties, bool) {
+	if !d.dataTransform() || d.sdkService == nil {
+		return models.ResourceProperties{}, false
+	}
+	resource, ok := d.sdkService.DeviceResource(deviceName, resourceName)
+	if !ok {
+		return models.ResourceProperties{}, false
+	}
+	return resource.Properties, true
+}
+
+func hasTransform(props models.ResourceProperties) bool {
+	return props.Mask != nil || props.Shift != nil || props.Base != nil || props.Scale != nil || props.Offset != nil
+}
+
+// transformReading applies the mask, shift, base, scale and offset of the resource to a raw
+// reading, in the same order as the EdgeX SDK. The result is checked against the range of the
+// value type of the resource, so that overflows caused by the transformation are detected.
+func transformReading(valueType string, props models.ResourceProperties, reading interface{}) (interface{}, error) {
+	valueRange, ok := valueTypeRanges[valueType]
+	if !ok || !hasTransform(props) {
+		return reading, nil
+	}
+
+	if props.Mask != nil || props.Shift != nil {
+		bits, err := readingBits(valueType, reading)
+		if err != nil {
+			return nil, fmt.Errorf("unable to apply mask and shift: %v", err)
+		}
+		if props.Mask != nil {
+			bits &= *props.Mask
+		}
+		if props.Shift != nil {
+			if *props.Shift > 0 {
+				bits <<= uint64(*props.Shift)
+			} else {
+				bits >>= uint64(-*props.Shift)
+			}
+		}
+		reading = valueTypeBitsValue(valueType, bits)
+	}
+	if props.Base == nil && props.Scale == nil && props.Offset == nil {
+		return reading, checkTransformedRange(valueType, valueRange, cast.ToFloat64(reading))
+	}
+
+	value, err := cast.ToFloat64E(reading)
+	if err != nil {
+		return nil, fmt.Errorf("unable to transform reading: %v", err)
+	}
+	if props.Base != nil {
+		value = math.Pow(*props.Base, value)
+	}
+	if props.Scale != nil {
+		value *= *props.Scale
+	}
+	if props.Offset != nil {
+		value += *props.Offset
+	}
+
+	return value, checkTransformedRange(valueType, valueRange, value)
+}
+
+// inverseTransformValue reverts the offset, scale and base of the resource on a value to be
+// written, and checks the raw value against the range of the value type. Mask and shift can
+// not be reverted and are not applied to writes.
+func inverseTransformValue(valueType string, props models.ResourceProperties, value interface{}) (interface{}, error) {
+	valueRange, ok := valueTypeRanges[valueType]
+	if !ok || (props.Base == nil && props.Scale == nil && props.Offset == nil) {
+		return value, nil
+	}
+
+	raw, err := cast.ToFloat64E(value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to transform value: %v", err)
+	}
+	if props.Offset != nil {
+		raw -= *props.Offset
+	}
+	if props.Scale != nil {
+		if *props.Scale == 0 {
+			return nil, fmt.Errorf("unable to revert a scale of 0")
+		}
+		raw /= *props.Scale
+	}
+	if props.Base != nil {
+		raw = math.Log(raw) / math.Log(*props.Base)
+	}
+	if valueType != common.ValueTypeFloat32 && valueType != common.ValueTypeFloat64 {
+		// avoid truncating values such as 9.999999 written to integer nodes
+		raw = math.Round(raw)
+	}
+	if err := checkTransformedRange(valueType, valueRange, raw); err != nil {
+		return nil, err
+	}
+
+	return castValueType(valueType, raw)
+}
+
+func checkTransformedRange(valueType string, valueRange [2]float64, value float64) error {
+	if math.IsNaN(value) || value < valueRange[0] || value > valueRange[1] {
+		return fmt.Errorf("transformed value %v overflows the value type(%v)", value, valueType)
+	}
+	return nil
+}
+
+// readingBits returns the bit pattern of an integer reading. The pattern of negative readings
+// is limited to the width of the value type, or of the reading for other value types, as the
+// SDK masks and shifts values of the value type rather than their sign extension.
+func readingBits(valueType string, reading interface{}) (uint64, error) {
+	rv := reflect.ValueOf(reading)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := uint64(rv.Int()) // #nosec G115
+		width, ok := valueTypeBits[valueType]
+		if !ok {
+			width = rv.Type().Bits()
+		}
+		if width < 64 {
+			bits &= 1<<width - 1
+		}
+		return bits, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	}
+	return 0, fmt.Errorf("reading %v of type %T is not an integer", reading, reading)
+}
+
+// valueTypeBitsValue converts a masked or shifted bit pattern back into the integer value type of
+// the resource, wrapping around its width as the SDK does. Other value types keep the pattern.
+func valueTypeBitsValue(valueType string, bits uint64) interface{} {
+	switch valueType {
+	case common.ValueTypeUint8:
+		return uint8(bits) // #nosec G115
+	case common.ValueTypeUint16:
+		return uint16(bits) // #nosec G115
+	case common.ValueTypeUint32:
+		return uint32(bits) // #nosec G115
+	case common.ValueTypeUint64:
+		return bits
+	case common.ValueTypeInt8:
+		return int8(bits) // #nosec G115
+	case common.ValueTypeInt16:
+		return int16(bits) // #nosec G115
+	case common.ValueTypeInt32:
+		return int32(bits) // #nosec G115
+	case common.ValueTypeInt64:
+		return int64(bits) // #nosec G115
+	}
+	return bits
+}
+
+// castValueType casts a transformed value back into the value type of the resource
+func castValueType(valueType string, value float64) (interface{}, error) {
+	switch valueType {
+	case common.ValueTypeUint8:
+		return cast.ToUint8E(value)
+	case common.ValueTypeUint16:
+		return cast.ToUint16E(value)
+	case common.ValueTypeUint32:
+		return cast.ToUint32E(value)
+	case common.ValueTypeUint64:
+		return cast.ToUint64E(value)
+	case common.ValueTypeInt8:
+		return cast.ToInt8E(value)
+	case common.ValueTypeInt16:
+		return cast.ToInt16E(value)
+	case common.ValueTypeInt32:
+		return cast.ToInt32E(value)
+	case common.ValueTypeInt64:
+		return cast.ToInt64E(value)
+	case common.ValueTypeFloat32:
+		return cast.ToFloat32E(value)
+	}
+	return value, nil
+}
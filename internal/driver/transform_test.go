@@ -0,0 +1,196 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func float64Ptr(v float64) *float64 { return &v }
+func uint64Ptr(v uint64) *uint64    { return &v }
+func int64Ptr(v int64) *int64       { return &v }
+
+func Test_transformReading(t *testing.T) {
+	tests := []struct {
+		name      string
+		valueType string
+		props     models.ResourceProperties
+		reading   interface{}
+		want      interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "OK - no transformation",
+			valueType: common.ValueTypeInt16,
+			reading:   int16(5),
+			want:      int16(5),
+		},
+		{
+			name:      "OK - scale and offset",
+			valueType: common.ValueTypeFloat64,
+			props:     models.ResourceProperties{Scale: float64Ptr(0.1), Offset: float64Ptr(-40)},
+			reading:   int16(655),
+			want:      25.5,
+		},
+		{
+			name:      "OK - scaled into the range of the value type",
+			valueType: common.ValueTypeInt16,
+			props:     models.ResourceProperties{Scale: float64Ptr(0.001)},
+			reading:   int32(1000000),
+			want:      1000.0,
+		},
+		{
+			name:      "OK - base",
+			valueType: common.ValueTypeUint32,
+			props:     models.ResourceProperties{Base: float64Ptr(2)},
+			reading:   uint8(10),
+			want:      1024.0,
+		},
+		{
+			name:      "OK - mask and shift",
+			valueType: common.ValueTypeUint8,
+			props:     models.ResourceProperties{Mask: uint64Ptr(0xF0), Shift: int64Ptr(-4)},
+			reading:   uint16(0xAB),
+			want:      uint8(0x0A),
+		},
+		{
+			name:      "OK - mask of a signed value",
+			valueType: common.ValueTypeUint8,
+			props:     models.ResourceProperties{Mask: uint64Ptr(0xFF)},
+			reading:   int16(-1),
+			want:      uint8(0xFF),
+		},
+		{
+			name:      "OK - mask of a negative value within the value type",
+			valueType: common.ValueTypeInt16,
+			props:     models.ResourceProperties{Mask: uint64Ptr(0xFFFF)},
+			reading:   int16(-1),
+			want:      int16(-1),
+		},
+		{
+			name:      "OK - shift of a negative value",
+			valueType: common.ValueTypeInt16,
+			props:     models.ResourceProperties{Shift: int64Ptr(-8)},
+			reading:   int16(-1),
+			want:      int16(0xFF),
+		},
+		{
+			name:      "OK - mask of a negative value wider than the value type",
+			valueType: common.ValueTypeInt8,
+			props:     models.ResourceProperties{Mask: uint64Ptr(0x7F)},
+			reading:   int32(-2),
+			want:      int8(0x7E),
+		},
+		{
+			name:      "NOK - overflow after scaling",
+			valueType: common.ValueTypeInt8,
+			props:     models.ResourceProperties{Scale: float64Ptr(10)},
+			reading:   int8(100),
+			wantErr:   true,
+		},
+		{
+			name:      "OK - shift wrapped around the value type",
+			valueType: common.ValueTypeUint8,
+			props:     models.ResourceProperties{Shift: int64Ptr(4)},
+			reading:   uint8(0xFF),
+			want:      uint8(0xF0),
+		},
+		{
+			name:      "NOK - mask of a float",
+			valueType: common.ValueTypeUint8,
+			props:     models.ResourceProperties{Mask: uint64Ptr(0xFF)},
+			reading:   1.5,
+			wantErr:   true,
+		},
+		{
+			name:      "OK - strings are not transformed",
+			valueType: common.ValueTypeString,
+			props:     models.ResourceProperties{Scale: float64Ptr(10)},
+			reading:   "10",
+			want:      "10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := transformReading(tt.valueType, tt.props, tt.reading)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if _, ok := tt.want.(float64); !ok {
+				assert.Equal(t, tt.want, got)
+				return
+			}
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func Test_inverseTransformValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		valueType string
+		props     models.ResourceProperties
+		value     interface{}
+		want      interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "OK - no transformation",
+			valueType: common.ValueTypeInt16,
+			props:     models.ResourceProperties{Mask: uint64Ptr(0xFF)},
+			value:     int16(5),
+			want:      int16(5),
+		},
+		{
+			name:      "OK - scale and offset",
+			valueType: common.ValueTypeFloat64,
+			props:     models.ResourceProperties{Scale: float64Ptr(0.1), Offset: float64Ptr(-40)},
+			value:     25.5,
+			want:      655.0,
+		},
+		{
+			name:      "OK - base",
+			valueType: common.ValueTypeUint32,
+			props:     models.ResourceProperties{Base: float64Ptr(2)},
+			value:     uint32(1024),
+			want:      uint32(10),
+		},
+		{
+			name:      "NOK - raw value overflows",
+			valueType: common.ValueTypeInt16,
+			props:     models.ResourceProperties{Scale: float64Ptr(0.1)},
+			value:     int16(5000),
+			wantErr:   true,
+		},
+		{
+			name:      "NOK - scale of 0",
+			valueType: common.ValueTypeInt16,
+			props:     models.ResourceProperties{Scale: float64Ptr(0)},
+			value:     int16(1),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := inverseTransformValue(tt.valueType, tt.props, tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-9)
+			assert.IsType(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+
+	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+)
+
+const engineeringUnitsProperty = "EngineeringUnits"
+
+// prepareUnits reads the EngineeringUnits property of the node of a resource once,
+// when the device profile does not define the units of the resource itself
+func (d *Driver) prepareUnits(deviceName string, client *opcua.Client, resourceName string, attrs map[string]interface{}) {
+	key, ok := d.valueNodeKey(deviceName, attrs)
+	if !ok || d.sdkService == nil {
+		return
+	}
+	if resource, ok := d.sdkService.DeviceResource(deviceName, resourceName); !ok || resource.Properties.Units != "" {
+		return
+	}
+
+	d.unitsMu.Lock()
+	_, cached := d.engineeringUnits[key]
+	d.unitsMu.Unlock()
+	if cached {
+		return
+	}
+
+	nodeID, _ := d.cachedNodeID(deviceName, attrs, NODE)
+	units, err := readEngineeringUnits(d.serviceContext(), client, nodeID)
+	if err != nil {
+		d.Logger.Debugf("Unable to read the engineering units of node %s: %v", nodeID, err)
+		return
+	}
+
+	d.unitsMu.Lock()
+	defer d.unitsMu.Unlock()
+	if d.engineeringUnits == nil {
+		d.engineeringUnits = make(map[string]string)
+	}
+	// nodes without engineering units are cached as well, so they are not browsed again
+	d.engineeringUnits[key] = units
+}
+
+// readEngineeringUnits returns the display name of the EngineeringUnits property of a
+// variable, or an empty string when the variable has no such property or it holds no value
+func readEngineeringUnits(ctx context.Context, client *opcua.Client, nodeID *ua.NodeID) (string, error) {
+	propertyID, err := client.Node(nodeID).TranslateBrowsePathsToNodeIDs(ctx, []*ua.QualifiedName{{Name: engineeringUnitsProperty}})
+	if err != nil {
+		if err == ua.StatusBadNoMatch {
+			return "", nil
+		}
+		return "", err
+	}
+
+	value, err := client.Node(propertyID).Value(ctx)
+	if err != nil {
+		return "", err
+	}
+	if value == nil {
+		// a Good property without a value defines no units
+		return "", nil
+	}
+	return euInformationUnits(value.Value()), nil
+}
+
+// euInformationUnits returns the units of an EUInformation value
+func euInformationUnits(value interface{}) string {
+	if eo, ok := value.(*ua.ExtensionObject); ok {
+		value = eo.Value
+	}
+	information, ok := value.(*ua.EUInformation)
+	if !ok || information.DisplayName == nil {
+		return ""
+	}
+	return information.DisplayName.Text
+}
+
+// setUnitsTag attaches the cached engineering units of the node to the reading
+func (d *Driver) setUnitsTag(deviceName string, req sdkModel.CommandRequest, result *sdkModel.CommandValue) {
+	key, ok := d.valueNodeKey(deviceName, req.Attributes)
+	if !ok {
+		return
+	}
+
+	d.unitsMu.Lock()
+	units := d.engineeringUnits[key]
+	d.unitsMu.Unlock()
+	if units == "" {
+		return
+	}
+	if result.Tags == nil {
+		result.Tags = make(map[string]string)
+	}
+	result.Tags[UnitsTag] = units
+}
@@ -0,0 +1,67 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//
+// Copyright (C) 2024 YIQISOFT
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"testing"
+
+	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_euInformationUnits(t *testing.T) {
+	celsius := &ua.EUInformation{UnitID: 4408652, DisplayName: ua.NewLocalizedText("°C")}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"EUInformation", celsius, "°C"},
+		{"ExtensionObject", &ua.ExtensionObject{Value: celsius}, "°C"},
+		{"without display name", &ua.EUInformation{UnitID: 4408652}, ""},
+		{"other value", "°C", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, euInformationUnits(tt.value))
+		})
+	}
+}
+
+func TestDriver_setUnitsTag(t *testing.T) {
+	d := &Driver{engineeringUnits: map[string]string{
+		"Test/ns=2;s=Temperature": "°C",
+		"Test/ns=2;s=Counter":     "",
+	}}
+	dataValue := &ua.DataValue{Value: ua.MustVariant(21.5), Status: ua.StatusOK}
+
+	tests := []struct {
+		name       string
+		attributes map[string]interface{}
+		want       string
+		wantTag    bool
+	}{
+		{"units of the node", map[string]interface{}{NODE: "ns=2;s=Temperature"}, "°C", true},
+		{"node without units", map[string]interface{}{NODE: "ns=2;s=Counter"}, "", false},
+		{"units not read yet", map[string]interface{}{NODE: "ns=2;s=Pressure"}, "", false},
+		{"other attribute", map[string]interface{}{NODE: "ns=2;s=Temperature", ATTRIBUTE: "DisplayName"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := sdkModel.CommandRequest{DeviceResourceName: "Temperature", Attributes: tt.attributes, Type: common.ValueTypeFloat64}
+			got, err := d.newDataValueResult("Test", req, dataValue)
+			require.NoError(t, err)
+			units, ok := got.Tags[UnitsTag]
+			assert.Equal(t, tt.wantTag, ok)
+			assert.Equal(t, tt.want, units)
+		})
+	}
+}
@@ -10,8 +10,12 @@
 package driver
 
 import (
-	"context"
+	"encoding/base64"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
@@ -36,18 +40,23 @@ func (d *Driver) HandleWriteCommands(deviceName string, protocols map[string]mod
 		return err
 	}
 
-	client, cliErr := d.buildClient(context.Background(), endpoint)
+	settings, err := fetchDeviceSettings(protocols)
+	if err != nil {
+		return err
+	}
+
+	client, cliErr := d.buildClient(deviceName, endpoint, settings)
 	if cliErr != nil {
-		d.Logger.Warnf("Driver.HandleReadCommands: Failed to connect OPCUA client, %s", err)
+		d.Logger.Warnf("Driver.HandleWriteCommands: Failed to connect OPCUA client, %s", cliErr)
 		return cliErr
 	}
 
-	return d.processWriteCommands(client, reqs, params)
+	return d.processWriteCommands(deviceName, client, settings, reqs, params)
 }
 
-func (d *Driver) processWriteCommands(client *opcua.Client, reqs []sdkModel.CommandRequest, params []*sdkModel.CommandValue) error {
+func (d *Driver) processWriteCommands(deviceName string, client *opcua.Client, settings deviceSettings, reqs []sdkModel.CommandRequest, params []*sdkModel.CommandValue) error {
 	for i, req := range reqs {
-		err := d.handleWriteCommandRequest(client, req, params[i])
+		err := d.handleWriteCommandRequest(deviceName, client, settings, req, params[i])
 		if err != nil {
 			d.Logger.Errorf("Driver.HandleWriteCommands: Handle write commands failed: %v", err)
 			return err
@@ -57,25 +66,71 @@ func (d *Driver) processWriteCommands(client *opcua.Client, reqs []sdkModel.Comm
 	return nil
 }
 
-func (d *Driver) handleWriteCommandRequest(deviceClient *opcua.Client, req sdkModel.CommandRequest,
+func (d *Driver) handleWriteCommandRequest(deviceName string, deviceClient *opcua.Client, settings deviceSettings, req sdkModel.CommandRequest,
 	param *sdkModel.CommandValue) error {
-	nodeID, err := getNodeID(req.Attributes, NODE)
+	if _, isMethod := req.Attributes[METHOD]; isMethod {
+		return d.makeMethodWrite(deviceName, deviceClient, settings, req, param)
+	}
+
+	id, err := d.resourceNodeID(deviceName, deviceClient, req.Attributes, NODE)
 	if err != nil {
 		return fmt.Errorf("Driver.handleWriteCommands: %v", err)
 	}
 
-	// get NewNodeID
-	id, err := ua.ParseNodeID(nodeID)
+	attributeID, err := getAttributeID(req.Attributes)
 	if err != nil {
-		return fmt.Errorf("Driver.handleWriteCommands: Invalid node id=%s", nodeID)
+		return fmt.Errorf("Driver.handleWriteCommands: %v", err)
+	}
+
+	indexRange, err := getIndexRange(req.Attributes)
+	if err != nil {
+		return fmt.Errorf("Driver.handleWriteCommands: %v", err)
 	}
 
 	value, err := newCommandValue(req.Type, param)
 	if err != nil {
 		return err
 	}
+	if props, ok := d.resourceProperties(deviceName, req.DeviceResourceName); ok {
+		value, err = inverseTransformValue(req.Type, props, value)
+		if err != nil {
+			return fmt.Errorf("Driver.handleWriteCommands: %v", err)
+		}
+	}
+
+	value, err = attributeWriteValue(attributeID, value)
+	if err != nil {
+		return fmt.Errorf("Driver.handleWriteCommands: %v", err)
+	}
+	if attributeID == ua.AttributeIDValue && builtinWriteTypes[req.Type] {
+		// the value may need to be converted into a built-in type of the node
+		def, err := d.nodeDataTypeDefinition(deviceName, deviceClient, id)
+		if err != nil {
+			d.Logger.Debugf("Driver.handleWriteCommands: %v", err)
+		} else {
+			if value, err = enumWriteValue(def, value); err != nil {
+				return fmt.Errorf("Driver.handleWriteCommands: %v", err)
+			}
+			if def.builtin == ua.TypeIDByteString && isBase64Encoded(req.Attributes) {
+				if value, err = base64WriteValue(value); err != nil {
+					return fmt.Errorf("Driver.handleWriteCommands: %v", err)
+				}
+			}
+			if value, err = builtinWriteValue(def.builtin, value); err != nil {
+				return fmt.Errorf("Driver.handleWriteCommands: %v", err)
+			}
+		}
+	}
+	if indexRange != "" {
+		value = indexRangeWriteValue(value)
+	}
 
-	v, err := ua.NewVariant(value)
+	var v *ua.Variant
+	if req.Type == common.ValueTypeObject && attributeID == ua.AttributeIDValue {
+		v, err = d.newStructureWriteValue(deviceName, deviceClient, id, value)
+	} else {
+		v, err = ua.NewVariant(value)
+	}
 	if err != nil {
 		return fmt.Errorf("Driver.handleWriteCommands: invalid value: %v", err)
 	}
@@ -83,8 +138,9 @@ func (d *Driver) handleWriteCommandRequest(deviceClient *opcua.Client, req sdkMo
 	request := &ua.WriteRequest{
 		NodesToWrite: []*ua.WriteValue{
 			{
-				NodeID:      id,
-				AttributeID: ua.AttributeIDValue,
+				NodeID:      d.registeredNodeID(deviceName, deviceClient, settings, req.DeviceResourceName, id),
+				AttributeID: attributeID,
+				IndexRange:  indexRange,
 				Value: &ua.DataValue{
 					EncodingMask: ua.DataValueValue, // encoding mask
 					Value:        v,
@@ -93,16 +149,203 @@ func (d *Driver) handleWriteCommandRequest(deviceClient *opcua.Client, req sdkMo
 		},
 	}
 
-	ctx := context.Background()
+	ctx, cancel := d.requestContext(settings)
+	defer cancel()
 	resp, err := deviceClient.Write(ctx, request)
 	if err != nil {
 		d.Logger.Errorf("Driver.handleWriteCommands: Write value %v failed: %s", v, err)
 		return err
 	}
+	d.checkNodeStatus(deviceClient, resp.Results[0])
 	d.Logger.Infof("Driver.handleWriteCommands: write sucessfully, %v", resp.Results[0])
 	return nil
 }
 
+// indexRangeWriteValue wraps a scalar value into a single element array,
+// as required to write one element of an array node
+func indexRangeWriteValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte, ua.ByteArray:
+		return value
+	case uint8:
+		return ua.ByteArray{v}
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice {
+		return value
+	}
+	array := reflect.MakeSlice(reflect.SliceOf(rv.Type()), 1, 1)
+	array.Index(0).Set(rv)
+	return array.Interface()
+}
+
+// writableAttributes lists the node attributes which can be written besides the Value
+var writableAttributes = map[ua.AttributeID]bool{
+	ua.AttributeIDValue:                   true,
+	ua.AttributeIDDisplayName:             true,
+	ua.AttributeIDDescription:             true,
+	ua.AttributeIDWriteMask:               true,
+	ua.AttributeIDUserWriteMask:           true,
+	ua.AttributeIDEventNotifier:           true,
+	ua.AttributeIDAccessLevel:             true,
+	ua.AttributeIDUserAccessLevel:         true,
+	ua.AttributeIDMinimumSamplingInterval: true,
+	ua.AttributeIDHistorizing:             true,
+}
+
+// attributeWriteValue converts the command value into the data type of the attribute
+func attributeWriteValue(attributeID ua.AttributeID, value interface{}) (interface{}, error) {
+	if !writableAttributes[attributeID] {
+		return nil, fmt.Errorf("attribute %s is not writable", strings.TrimPrefix(attributeID.String(), "AttributeID"))
+	}
+
+	switch attributeID {
+	case ua.AttributeIDDisplayName, ua.AttributeIDDescription:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("attribute %s requires a String value", strings.TrimPrefix(attributeID.String(), "AttributeID"))
+		}
+		return ua.NewLocalizedText(text), nil
+	}
+
+	return value, nil
+}
+
+// builtinWriteTypes lists the value types which are converted into the
+// OPC UA built-in type of the node before writing
+var builtinWriteTypes = map[string]bool{
+	common.ValueTypeString: true,
+	common.ValueTypeBinary: true,
+	common.ValueTypeInt64:  true,
+	common.ValueTypeUint64: true,
+	common.ValueTypeUint32: true,
+}
+
+// builtinWriteValue converts a command value into the OPC UA built-in type of
+// the node, using the same mappings as the readings of these types
+func builtinWriteValue(typeID ua.TypeID, value interface{}) (interface{}, error) {
+	switch typeID {
+	case ua.TypeIDDateTime:
+		switch v := value.(type) {
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DateTime %q: %v", v, err)
+			}
+			return t, nil
+		case int64:
+			return time.Unix(0, v).UTC(), nil
+		case uint64:
+			return time.Unix(0, int64(v)).UTC(), nil // #nosec G115
+		}
+	case ua.TypeIDGUID:
+		if v, ok := value.(string); ok {
+			guid := ua.NewGUID(v)
+			if guid == nil {
+				return nil, fmt.Errorf("invalid Guid %q", v)
+			}
+			return guid, nil
+		}
+	case ua.TypeIDByteString:
+		if v, ok := value.(string); ok {
+			return []byte(v), nil
+		}
+	case ua.TypeIDByte:
+		// a Binary value written to an array of Byte
+		if v, ok := value.([]byte); ok {
+			return ua.ByteArray(v), nil
+		}
+	case ua.TypeIDLocalizedText:
+		if v, ok := value.(string); ok {
+			return ua.NewLocalizedText(v), nil
+		}
+	case ua.TypeIDQualifiedName:
+		if v, ok := value.(string); ok {
+			return parseQualifiedName(v)
+		}
+	case ua.TypeIDNodeID:
+		if v, ok := value.(string); ok {
+			return ua.ParseNodeID(v)
+		}
+	case ua.TypeIDExpandedNodeID:
+		if v, ok := value.(string); ok {
+			return ua.ParseExpandedNodeID(v, nil)
+		}
+	case ua.TypeIDXMLElement:
+		if v, ok := value.(string); ok {
+			return ua.XMLElement(v), nil
+		}
+	case ua.TypeIDStatusCode:
+		if v, ok := value.(uint32); ok {
+			return ua.StatusCode(v), nil
+		}
+	}
+
+	return value, nil
+}
+
+// base64WriteValue decodes a ByteString value written as a base64 string
+func base64WriteValue(value interface{}) (interface{}, error) {
+	v, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 ByteString: %v", err)
+	}
+	return b, nil
+}
+
+// parseQualifiedName parses a QualifiedName formatted as [<namespace index>:]<name>
+func parseQualifiedName(s string) (*ua.QualifiedName, error) {
+	if ns, name, found := strings.Cut(s, ":"); found {
+		if index, err := strconv.ParseUint(ns, 10, 16); err == nil {
+			return &ua.QualifiedName{NamespaceIndex: uint16(index), Name: name}, nil
+		}
+	}
+	// no namespace index, the name may contain a colon itself
+	return &ua.QualifiedName{Name: s}, nil
+}
+
+// makeMethodWrite calls the method of a resource with the value of a set command as input
+func (d *Driver) makeMethodWrite(deviceName string, deviceClient *opcua.Client, settings deviceSettings, req sdkModel.CommandRequest,
+	param *sdkModel.CommandValue) error {
+	oid, mid, inputMap, err := d.methodAttributes(deviceName, deviceClient, req.Attributes)
+	if err != nil {
+		return fmt.Errorf("Driver.handleWriteCommands: %v", err)
+	}
+
+	value, err := newCommandValue(req.Type, param)
+	if err != nil {
+		return fmt.Errorf("Driver.handleWriteCommands: %v", err)
+	}
+
+	sig, err := d.methodSignature(deviceName, deviceClient, mid)
+	if err != nil {
+		return fmt.Errorf("Driver.handleWriteCommands: %v", err)
+	}
+	inputs, err := methodWriteParameters(sig.inputs, value)
+	if err != nil {
+		return fmt.Errorf("Driver.handleWriteCommands: %v", err)
+	}
+	params := queryParameters(req.Attributes)
+	if params == nil {
+		params = make(map[string]interface{}, len(inputs))
+	}
+	for name, v := range inputs {
+		params[name] = v
+	}
+
+	resp, err := d.callMethod(deviceName, deviceClient, settings, oid, mid, inputMap, params)
+	if err != nil {
+		return fmt.Errorf("Driver.handleWriteCommands: %v", err)
+	}
+	d.Logger.Infof("Method %s of device %s called: %s", mid, deviceName, statusName(resp.StatusCode))
+	return nil
+}
+
 func newCommandValue(valueType string, param *sdkModel.CommandValue) (interface{}, error) {
 	var commandValue interface{}
 	var err error
@@ -131,6 +374,37 @@ func newCommandValue(valueType string, param *sdkModel.CommandValue) (interface{
 		commandValue, err = param.Float32Value()
 	case common.ValueTypeFloat64:
 		commandValue, err = param.Float64Value()
+	case common.ValueTypeBoolArray:
+		commandValue, err = param.BoolArrayValue()
+	case common.ValueTypeStringArray:
+		commandValue, err = param.StringArrayValue()
+	case common.ValueTypeUint8Array:
+		var bytes []uint8
+		// a plain []byte would be written as ByteString instead of an array of Byte
+		bytes, err = param.Uint8ArrayValue()
+		commandValue = ua.ByteArray(bytes)
+	case common.ValueTypeUint16Array:
+		commandValue, err = param.Uint16ArrayValue()
+	case common.ValueTypeUint32Array:
+		commandValue, err = param.Uint32ArrayValue()
+	case common.ValueTypeUint64Array:
+		commandValue, err = param.Uint64ArrayValue()
+	case common.ValueTypeInt8Array:
+		commandValue, err = param.Int8ArrayValue()
+	case common.ValueTypeInt16Array:
+		commandValue, err = param.Int16ArrayValue()
+	case common.ValueTypeInt32Array:
+		commandValue, err = param.Int32ArrayValue()
+	case common.ValueTypeInt64Array:
+		commandValue, err = param.Int64ArrayValue()
+	case common.ValueTypeFloat32Array:
+		commandValue, err = param.Float32ArrayValue()
+	case common.ValueTypeFloat64Array:
+		commandValue, err = param.Float64ArrayValue()
+	case common.ValueTypeBinary:
+		commandValue, err = param.BinaryValue()
+	case common.ValueTypeObject:
+		commandValue, err = param.ObjectValue()
 	default:
 		err = fmt.Errorf("fail to convert param, none supported value type: %v", valueType)
 	}
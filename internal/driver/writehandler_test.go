@@ -10,9 +10,11 @@ package driver
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	sdkModel "github.com/edgexfoundry/device-sdk-go/v4/pkg/models"
 	"github.com/edgexfoundry/go-mod-core-contracts/v4/common"
+	"github.com/gopcua/opcua/ua"
 )
 
 //comment out following unittest as it requires to run a Python-based simulated OPC UA server, which is not available
@@ -110,7 +112,7 @@ import (
 //		t.Run(tt.name, func(t *testing.T) {
 //			d := &Driver{
 //				Logger:    &logger.MockLogger{},
-//				clientMap: make(map[string]*opcua.Client),
+//				clientMap: make(map[clientKey]*opcua.Client),
 //			}
 //			if err := d.HandleWriteCommands(tt.args.deviceName, tt.args.protocols, tt.args.reqs, tt.args.params); (err != nil) != tt.wantErr {
 //				t.Errorf("Driver.HandleWriteCommands() error = %v, wantErr %v", err, tt.wantErr)
@@ -214,6 +216,36 @@ func Test_newCommandValue(t *testing.T) {
 			want:    float64(5),
 			wantErr: false,
 		},
+		{
+			name:    "OK - bool array value",
+			args:    args{valueType: common.ValueTypeBoolArray, param: &sdkModel.CommandValue{Value: []bool{true, false}, Type: common.ValueTypeBoolArray}},
+			want:    []bool{true, false},
+			wantErr: false,
+		},
+		{
+			name:    "OK - uint8 array value",
+			args:    args{valueType: common.ValueTypeUint8Array, param: &sdkModel.CommandValue{Value: []uint8{1, 2}, Type: common.ValueTypeUint8Array}},
+			want:    ua.ByteArray{1, 2},
+			wantErr: false,
+		},
+		{
+			name:    "OK - int32 array value",
+			args:    args{valueType: common.ValueTypeInt32Array, param: &sdkModel.CommandValue{Value: []int32{1, 2}, Type: common.ValueTypeInt32Array}},
+			want:    []int32{1, 2},
+			wantErr: false,
+		},
+		{
+			name:    "OK - float64 array value",
+			args:    args{valueType: common.ValueTypeFloat64Array, param: &sdkModel.CommandValue{Value: []float64{1.5}, Type: common.ValueTypeFloat64Array}},
+			want:    []float64{1.5},
+			wantErr: false,
+		},
+		{
+			name:    "OK - string array value",
+			args:    args{valueType: common.ValueTypeStringArray, param: &sdkModel.CommandValue{Value: []string{"a"}, Type: common.ValueTypeStringArray}},
+			want:    []string{"a"},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -228,3 +260,141 @@ func Test_newCommandValue(t *testing.T) {
 		})
 	}
 }
+
+func Test_attributeWriteValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		attributeID ua.AttributeID
+		value       interface{}
+		want        interface{}
+		wantErr     bool
+	}{
+		{
+			name:        "OK - value attribute",
+			attributeID: ua.AttributeIDValue,
+			value:       int32(5),
+			want:        int32(5),
+		},
+		{
+			name:        "OK - display name attribute",
+			attributeID: ua.AttributeIDDisplayName,
+			value:       "Speed",
+			want:        ua.NewLocalizedText("Speed"),
+		},
+		{
+			name:        "OK - access level attribute",
+			attributeID: ua.AttributeIDAccessLevel,
+			value:       uint8(3),
+			want:        uint8(3),
+		},
+		{
+			name:        "NOK - description attribute requires a string",
+			attributeID: ua.AttributeIDDescription,
+			value:       int32(5),
+			wantErr:     true,
+		},
+		{
+			name:        "NOK - data type attribute is not writable",
+			attributeID: ua.AttributeIDDataType,
+			value:       "i=6",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := attributeWriteValue(tt.attributeID, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("attributeWriteValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("attributeWriteValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_indexRangeWriteValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{name: "OK - scalar is wrapped", value: int32(5), want: []int32{5}},
+		{name: "OK - byte is wrapped into a byte array", value: uint8(5), want: ua.ByteArray{5}},
+		{name: "OK - array is kept", value: []float64{1, 2}, want: []float64{1, 2}},
+		{name: "OK - byte string is kept", value: []byte("ab"), want: []byte("ab")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := indexRangeWriteValue(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("indexRangeWriteValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_builtinWriteValue(t *testing.T) {
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	tests := []struct {
+		name    string
+		typeID  ua.TypeID
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "OK - DateTime from RFC3339", typeID: ua.TypeIDDateTime, value: "2024-01-02T03:04:05.000000006Z", want: timestamp},
+		{name: "OK - DateTime from epoch", typeID: ua.TypeIDDateTime, value: timestamp.UnixNano(), want: timestamp},
+		{name: "NOK - invalid DateTime", typeID: ua.TypeIDDateTime, value: "yesterday", wantErr: true},
+		{name: "OK - Guid", typeID: ua.TypeIDGUID, value: "72962B91-FA75-4AE6-8D28-B404DC7DAF63", want: ua.NewGUID("72962B91-FA75-4AE6-8D28-B404DC7DAF63")},
+		{name: "NOK - invalid Guid", typeID: ua.TypeIDGUID, value: "72962B91", wantErr: true},
+		{name: "OK - ByteString from a string", typeID: ua.TypeIDByteString, value: "ab", want: []byte{0x61, 0x62}},
+		{name: "OK - ByteString from binary", typeID: ua.TypeIDByteString, value: []byte{0x01}, want: []byte{0x01}},
+		{name: "OK - Byte array from binary", typeID: ua.TypeIDByte, value: []byte{0x01}, want: ua.ByteArray{0x01}},
+		{name: "OK - LocalizedText", typeID: ua.TypeIDLocalizedText, value: "Speed", want: ua.NewLocalizedText("Speed")},
+		{name: "OK - QualifiedName", typeID: ua.TypeIDQualifiedName, value: "2:Speed", want: &ua.QualifiedName{NamespaceIndex: 2, Name: "Speed"}},
+		{name: "OK - QualifiedName without namespace", typeID: ua.TypeIDQualifiedName, value: "a:b", want: &ua.QualifiedName{Name: "a:b"}},
+		{name: "OK - NodeId", typeID: ua.TypeIDNodeID, value: "ns=2;i=11", want: ua.MustParseNodeID("ns=2;i=11")},
+		{name: "NOK - invalid NodeId", typeID: ua.TypeIDNodeID, value: "ns=x", wantErr: true},
+		{name: "OK - StatusCode", typeID: ua.TypeIDStatusCode, value: uint32(0x800A0000), want: ua.StatusBadTimeout},
+		{name: "OK - String kept", typeID: ua.TypeIDString, value: "text", want: "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := builtinWriteValue(tt.typeID, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("builtinWriteValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("builtinWriteValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_base64WriteValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "OK - base64 string", value: "AQL/", want: []byte{0x01, 0x02, 0xff}},
+		{name: "OK - binary kept", value: []byte{0x01}, want: []byte{0x01}},
+		{name: "NOK - invalid base64", value: "%%%", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := base64WriteValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("base64WriteValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("base64WriteValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}